@@ -0,0 +1,157 @@
+package diagnostics
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// reserve makes room in the stack for item when its level has a capacity, it
+// returns false when item has to be dropped. It must be called with the lock
+// held.
+func (d *Diagnostics) reserve(item *DiagnosticItem) bool {
+	limit, ok := d.config.capacity[item.Level]
+	if !ok {
+		return true
+	}
+
+	d.updateIndex(d.dedupPolicy())
+	if d.levels[item.Level] < limit {
+		return true
+	}
+
+	if !d.config.ringBuffer || limit == 0 {
+		d.drop(item.Level, 1, item.Timestamp)
+		return false
+	}
+
+	for index, i := range d.stack {
+		if i.Level == item.Level && i != d.truncation {
+			d.removeItem(index)
+			d.drop(i.Level, 1, item.Timestamp)
+			break
+		}
+	}
+
+	return true
+}
+
+// trim drops the items of the levels over their capacity, the oldest ones with
+// a ring buffer and the newest ones otherwise. It must be called with the lock
+// held.
+func (d *Diagnostics) trim(at time.Time) {
+	d.updateIndex(d.dedupPolicy())
+	excess := map[DiagnosticLevel]int{}
+	for level, limit := range d.config.capacity {
+		if d.levels[level] > limit {
+			excess[level] = d.levels[level] - limit
+		}
+	}
+	if len(excess) == 0 {
+		return
+	}
+
+	kept := map[DiagnosticLevel]int{}
+	stack := make([]*DiagnosticItem, 0, len(d.stack))
+	for _, i := range d.stack {
+		limit, ok := d.config.capacity[i.Level]
+		if !ok || i == d.truncation {
+			stack = append(stack, i)
+			continue
+		}
+
+		kept[i.Level]++
+		if d.config.ringBuffer && kept[i.Level] > excess[i.Level] {
+			stack = append(stack, i)
+		} else if !d.config.ringBuffer && kept[i.Level] <= limit {
+			stack = append(stack, i)
+		}
+	}
+	d.stack = stack
+	d.index = nil
+
+	for level, count := range excess {
+		d.drop(level, count, at)
+	}
+}
+
+// drop counts the dropped items and reports them in the truncation item of the
+// diagnostics, it must be called with the lock held.
+func (d *Diagnostics) drop(level DiagnosticLevel, count int, at time.Time) {
+	if d.dropped == nil {
+		d.dropped = map[DiagnosticLevel]int{}
+	}
+	d.dropped[level] += count
+
+	if d.truncation == nil {
+		d.truncation = &DiagnosticItem{
+			Code:        TruncatedCode,
+			Description: "items were dropped because the capacity of the diagnostics was reached",
+			Level:       Warning,
+			Scope:       d.Scope(),
+			Timestamp:   at,
+		}
+		if !d.createdAt.IsZero() {
+			d.truncation.Elapsed = at.Sub(d.createdAt)
+		}
+		d.truncation.Sequence = d.nextSequence().Add(1)
+		d.stack = append(d.stack, d.truncation)
+	}
+
+	d.truncation.Attributes = droppedAttributes(d.dropped)
+	d.truncation.LastSeen = at
+}
+
+func droppedAttributes(dropped map[DiagnosticLevel]int) []slog.Attr {
+	levels := make([]DiagnosticLevel, 0, len(dropped))
+	for level := range dropped {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i] < levels[j]
+	})
+
+	result := make([]slog.Attr, 0, len(levels))
+	for _, level := range levels {
+		result = append(result, slog.Int(level.String(), dropped[level]))
+	}
+
+	return result
+}
+
+// restoreTruncation restores the dropped counters from an unmarshaled
+// truncation item, it must be called with the lock held.
+func (d *Diagnostics) restoreTruncation(item *DiagnosticItem) {
+	if item.Code != TruncatedCode || item.Level != Warning || d.truncation != nil {
+		return
+	}
+
+	d.truncation = item
+	d.dropped = map[DiagnosticLevel]int{}
+	for _, attr := range item.Attributes {
+		if level, err := ParseLevel(attr.Key); err == nil && attr.Value.Kind() == slog.KindInt64 {
+			d.dropped[level] = int(attr.Value.Int64())
+		}
+	}
+}
+
+// Dropped returns the number of items dropped per level by d and its children
+// because their capacity was reached.
+func (d *Diagnostics) Dropped() map[DiagnosticLevel]int {
+	result := map[DiagnosticLevel]int{}
+	d.countDropped(result)
+	return result
+}
+
+func (d *Diagnostics) countDropped(result map[DiagnosticLevel]int) {
+	d.mu.RLock()
+	for level, count := range d.dropped {
+		result[level] += count
+	}
+	children := d.children
+	d.mu.RUnlock()
+
+	for _, child := range children {
+		child.countDropped(result)
+	}
+}
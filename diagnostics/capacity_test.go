@@ -0,0 +1,131 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapacity(t *testing.T) {
+	t.Run("Drops new items", func(t *testing.T) {
+		d := New(WithCapacity(Trace, 2))
+		for i := 0; i < 5; i++ {
+			d.AddTrace(fmt.Sprintf("trace%d", i))
+			d.AddErrorWithCode(fmt.Sprintf("code%d", i), io.EOF)
+		}
+
+		assert.Equal(t, []string{"trace: trace0", "trace: trace1"}, d.Trace(), "Expected the first traces to be kept")
+		assert.Equal(t, 5, len(d.Errors()), "Expected every error to be kept")
+		assert.Equal(t, map[DiagnosticLevel]int{Trace: 3}, d.Dropped(), "Expected the dropped traces to be counted")
+	})
+
+	t.Run("Ring Buffer", func(t *testing.T) {
+		d := New(WithCapacity(Trace, 2), WithRingBuffer())
+		for i := 0; i < 5; i++ {
+			d.AddTrace(fmt.Sprintf("trace%d", i))
+		}
+
+		assert.Equal(t, []string{"trace: trace3", "trace: trace4"}, d.Trace(), "Expected the last traces to be kept")
+		assert.Equal(t, map[DiagnosticLevel]int{Trace: 3}, d.Dropped(), "Expected the evicted traces to be counted")
+	})
+
+	t.Run("Ring Buffer keeps the dedup index", func(t *testing.T) {
+		d := New(WithCapacity(Trace, 2), WithRingBuffer())
+		d.AddTrace("trace0")
+		d.AddTrace("trace1")
+		d.AddTrace("trace2")
+		d.AddTrace("trace2")
+		d.AddTrace("trace0")
+
+		assert.Equal(t, []string{"trace: trace2", "trace: trace0"}, d.Trace(), "Expected the evicted trace to be added again")
+		assert.Equal(t, 2, d.Snapshot()[1].Occurrences, "Expected the repeat of a kept trace to be counted")
+	})
+
+	t.Run("Zero capacity", func(t *testing.T) {
+		d := New(WithCapacity(Debug, 0), WithRingBuffer())
+		d.AddDebug("debug1")
+
+		assert.Equal(t, 1, d.Len(), "Expected only the truncation warning to be kept")
+		assert.Equal(t, map[DiagnosticLevel]int{Debug: 1}, d.Dropped(), "Expected the dropped item to be counted")
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		d := New(WithCapacity(Trace, 2))
+		d.AddTrace("trace0")
+		child := d.Child("import")
+		child.AddTrace("trace1")
+		child.AddTrace("trace2")
+		child.AddTrace("trace3")
+
+		d.Compact()
+
+		assert.Equal(t, []string{"trace: trace0", "trace: trace1"}, d.Trace(), "Expected the moved items to be limited by the parent capacity")
+		assert.Equal(t, map[DiagnosticLevel]int{Trace: 2}, d.Dropped(), "Expected the dropped items of the children to be kept")
+		assert.Equal(t, 1, len(d.Warnings()), "Expected a single truncation warning")
+	})
+
+	t.Run("Compact with Ring Buffer", func(t *testing.T) {
+		d := New(WithCapacity(Trace, 2), WithRingBuffer())
+		d.AddTrace("trace0")
+		d.Child("import").AddTrace("trace1")
+		d.AddTrace("trace2")
+
+		d.Compact()
+
+		assert.Equal(t, []string{"trace: trace1", "trace: trace2"}, d.Trace(), "Expected the oldest items to be dropped")
+		assert.Equal(t, map[DiagnosticLevel]int{Trace: 1}, d.Dropped(), "Expected the evicted item to be counted")
+	})
+
+	t.Run("Without capacity", func(t *testing.T) {
+		d := New()
+		d.AddTrace("trace1")
+
+		assert.Empty(t, d.Dropped(), "Expected no item to be dropped")
+		assert.Empty(t, d.Warnings(), "Expected no truncation warning")
+	})
+}
+
+func TestCapacity_Truncation(t *testing.T) {
+	d := New(WithCapacity(Trace, 1), WithCapacity(Info, 1))
+	d.AddTrace("trace1")
+	d.AddTrace("trace2")
+	d.AddInfo("info1")
+	d.AddInfo("info2")
+	d.AddInfo("info3")
+
+	assert.True(t, d.HasWarnings(), "Expected a truncation warning")
+	warnings := d.Warnings()
+	assert.Equal(t, 1, len(warnings), "Expected a single truncation warning")
+	assert.Contains(t, warnings[0], TruncatedCode, "Expected the truncation code")
+
+	var truncation *DiagnosticItem
+	for _, i := range d.Snapshot() {
+		if i.Code == TruncatedCode {
+			truncation = i
+		}
+	}
+	assert.Equal(t, []slog.Attr{slog.Int("Trace", 1), slog.Int("Info", 2)}, truncation.Attributes, "Expected the dropped items per level")
+	assert.Contains(t, d.String(), "{Trace=1 Info=2}", "Expected the dropped items to be rendered")
+
+	t.Run("Children", func(t *testing.T) {
+		child := d.Child("import")
+		child.AddTrace("trace1")
+		child.AddTrace("trace2")
+
+		assert.Equal(t, map[DiagnosticLevel]int{Trace: 2, Info: 2}, d.Dropped(), "Expected the children to have their own capacity")
+		assert.Equal(t, 2, len(d.Warnings()), "Expected a truncation warning per diagnostics")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		b, err := json.Marshal(d)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+
+		result := &Diagnostics{}
+		assert.NoError(t, json.Unmarshal(b, result), "Unexpected error while unmarshaling")
+		assert.Equal(t, d.Dropped(), result.Dropped(), "Expected the dropped counters to round trip")
+	})
+}
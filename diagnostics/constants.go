@@ -3,3 +3,41 @@ package diagnostics
 type TraceIDKey string
 
 const TraceID TraceIDKey = "traceID"
+
+const SchemaVersion = 2
+
+type contextKey string
+
+const (
+	diagnosticsContextKey  contextKey = "diagnostics"
+	traceContextContextKey contextKey = "traceContext"
+	metadataContextKey     contextKey = "metadata"
+)
+
+const (
+	TraceIDHeader     = "X-Trace-Id"
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+const (
+	TraceIDAttribute     = "traceId"
+	CodeAttribute        = "code"
+	ScopeAttribute       = "scope"
+	OccurrencesAttribute = "occurrences"
+)
+
+const (
+	TenantMetadata    = "tenant"
+	OperationMetadata = "operation"
+	UserMetadata      = "user"
+	VersionMetadata   = "version"
+	HostMetadata      = "host"
+)
+
+// TruncatedCode is the code of the warning reporting the items dropped by a
+// diagnostics with a capacity.
+const TruncatedCode = "TRUNCATED"
+
+// PanicCode is the code of the items recording a recovered panic.
+const PanicCode = "PANIC"
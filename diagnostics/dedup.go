@@ -0,0 +1,146 @@
+package diagnostics
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DedupPolicy decides which items are repeats of an item already added, items
+// with the same key are repeats. Items are never deduplicated when ok is false.
+type DedupPolicy interface {
+	Key(item *DiagnosticItem) (key string, ok bool)
+}
+
+// DedupFunc is a custom DedupPolicy.
+type DedupFunc func(item *DiagnosticItem) (string, bool)
+
+func (f DedupFunc) Key(item *DiagnosticItem) (string, bool) {
+	return f(item)
+}
+
+var (
+	// DedupNone keeps every item.
+	DedupNone DedupPolicy = DedupFunc(func(item *DiagnosticItem) (string, bool) {
+		return "", false
+	})
+	// DedupExact deduplicates the items with the same code, description,
+	// level, scope and attributes.
+	DedupExact DedupPolicy = DedupFunc(func(item *DiagnosticItem) (string, bool) {
+		return dedupKey(item.Code, item.Description, item), true
+	})
+	// DedupCaseInsensitive is DedupExact ignoring the case of the code and of
+	// the description, it is the default policy.
+	DedupCaseInsensitive DedupPolicy = DedupFunc(func(item *DiagnosticItem) (string, bool) {
+		return dedupKey(strings.ToLower(item.Code), strings.ToLower(item.Description), item), true
+	})
+	// DedupByCode deduplicates the items with the same code in the same scope,
+	// items without a code are deduplicated as with DedupCaseInsensitive.
+	DedupByCode DedupPolicy = DedupFunc(func(item *DiagnosticItem) (string, bool) {
+		if item.Code == "" {
+			return DedupCaseInsensitive.Key(item)
+		}
+
+		return "code\x00" + strings.ToLower(item.Code) + "\x00" + item.Scope, true
+	})
+)
+
+func dedupKey(code string, description string, item *DiagnosticItem) string {
+	var result strings.Builder
+	result.WriteString(code)
+	result.WriteByte(0)
+	result.WriteString(description)
+	result.WriteByte(0)
+	result.WriteString(strconv.Itoa(int(item.Level)))
+	result.WriteByte(0)
+	result.WriteString(item.Scope)
+	for _, attr := range formatAttributes("", item.Attributes) {
+		result.WriteByte(0)
+		result.WriteString(attr)
+	}
+
+	return result.String()
+}
+
+func (d *Diagnostics) dedupPolicy() DedupPolicy {
+	if d.config.dedup != nil {
+		return d.config.dedup
+	}
+
+	return DedupCaseInsensitive
+}
+
+// findRepeat returns the item of the stack item is a repeat of, it must be
+// called with the lock held.
+func (d *Diagnostics) findRepeat(item *DiagnosticItem) *DiagnosticItem {
+	policy := d.dedupPolicy()
+	key, ok := policy.Key(item)
+	if !ok {
+		return nil
+	}
+
+	d.updateIndex(policy)
+	return d.index[key]
+}
+
+// updateIndex adds the items appended to the stack since the last call to the
+// dedup index and to the count of items per level, both are rebuilt when the
+// index was reset. It must be called with the lock held.
+func (d *Diagnostics) updateIndex(policy DedupPolicy) {
+	if d.index == nil || d.indexed > len(d.stack) {
+		d.index = make(map[string]*DiagnosticItem, len(d.stack))
+		d.levels = map[DiagnosticLevel]int{}
+		d.indexed = 0
+	}
+
+	for _, i := range d.stack[d.indexed:] {
+		if i == d.truncation {
+			continue
+		}
+
+		d.levels[i.Level]++
+		if key, ok := policy.Key(i); ok {
+			if _, exists := d.index[key]; !exists {
+				d.index[key] = i
+			}
+		}
+	}
+	d.indexed = len(d.stack)
+}
+
+// removeItem removes the item at index from the stack, from the dedup index
+// and from the index of a merge, it must be called with the lock held.
+func (d *Diagnostics) removeItem(index int) {
+	item := d.stack[index]
+	copy(d.stack[index:], d.stack[index+1:])
+	d.stack[len(d.stack)-1] = nil
+	d.stack = d.stack[:len(d.stack)-1]
+
+	if d.merging != nil {
+		d.merging.remove(item)
+	}
+
+	if d.index == nil || index >= d.indexed {
+		return
+	}
+
+	d.indexed--
+	if item == d.truncation {
+		return
+	}
+	d.levels[item.Level]--
+	if key, ok := d.dedupPolicy().Key(item); ok && d.index[key] == item {
+		delete(d.index, key)
+	}
+}
+
+// repeat counts the occurrences of item as occurrences of d.
+func (d *DiagnosticItem) repeat(item *DiagnosticItem) {
+	d.Occurrences = max(d.Occurrences, 1) + max(item.Occurrences, 1)
+	lastSeen := item.LastSeen
+	if lastSeen.IsZero() {
+		lastSeen = item.Timestamp
+	}
+	if lastSeen.After(d.LastSeen) {
+		d.LastSeen = lastSeen
+	}
+}
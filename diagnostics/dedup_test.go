@@ -0,0 +1,191 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupPolicy(t *testing.T) {
+	items := func() []*DiagnosticItem {
+		return []*DiagnosticItem{
+			{Code: "code1", Description: "description1", Level: Error},
+			{Code: "CODE1", Description: "Description1", Level: Error},
+			{Code: "code1", Description: "description2", Level: Error},
+			{Code: "code1", Description: "description1", Level: Error},
+			{Description: "description3", Level: Info},
+			{Description: "DESCRIPTION3", Level: Info},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		policy      DedupPolicy
+		expected    int
+		occurrences []int
+	}{
+		{
+			name:        "Default",
+			policy:      nil,
+			expected:    3,
+			occurrences: []int{3, 1, 2},
+		},
+		{
+			name:        "None",
+			policy:      DedupNone,
+			expected:    6,
+			occurrences: []int{1, 1, 1, 1, 1, 1},
+		},
+		{
+			name:        "Exact",
+			policy:      DedupExact,
+			expected:    5,
+			occurrences: []int{2, 1, 1, 1, 1},
+		},
+		{
+			name:        "Case Insensitive",
+			policy:      DedupCaseInsensitive,
+			expected:    3,
+			occurrences: []int{3, 1, 2},
+		},
+		{
+			name:        "By Code",
+			policy:      DedupByCode,
+			expected:    2,
+			occurrences: []int{4, 2},
+		},
+		{
+			name: "Custom",
+			policy: DedupFunc(func(item *DiagnosticItem) (string, bool) {
+				return item.Level.String(), true
+			}),
+			expected:    2,
+			occurrences: []int{4, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []Option{}
+			if tt.policy != nil {
+				options = append(options, WithDedupPolicy(tt.policy))
+			}
+			d := New(options...)
+			for _, i := range items() {
+				d.AddItem(i)
+			}
+
+			snapshot := d.Snapshot()
+			occurrences := []int{}
+			for _, i := range snapshot {
+				occurrences = append(occurrences, i.Occurrences)
+			}
+			assert.Equal(t, tt.expected, len(snapshot), "Unexpected number of items")
+			assert.Equal(t, tt.occurrences, occurrences, "Unexpected occurrences")
+		})
+	}
+
+	t.Run("Inherited by children", func(t *testing.T) {
+		d := New(WithDedupPolicy(DedupNone))
+		child := d.Child("import")
+		child.AddInfo("info1")
+		child.AddInfo("info1")
+
+		assert.Equal(t, 2, child.Len(), "Expected the child to use the policy of its parent")
+	})
+}
+
+func TestDedup_Index(t *testing.T) {
+	t.Run("Many items", func(t *testing.T) {
+		d := New()
+		for i := 0; i < 10000; i++ {
+			d.AddItem(&DiagnosticItem{Code: fmt.Sprintf("code%d", i%100), Description: "description1", Level: Info})
+		}
+
+		items := d.Snapshot()
+		assert.Equal(t, 100, len(items), "Expected the repeats to be found")
+		assert.Equal(t, "code0", items[0].Code, "Expected the insertion order to be kept")
+		assert.Equal(t, "code99", items[99].Code, "Expected the insertion order to be kept")
+		assert.Equal(t, 100, items[42].Occurrences, "Expected every repeat to be counted")
+	})
+
+	t.Run("Stack set directly", func(t *testing.T) {
+		d := New()
+		d.stack = []*DiagnosticItem{{Code: "code1", Description: "description1", Level: Info}}
+
+		d.AddItem(&DiagnosticItem{Code: "code1", Description: "description1", Level: Info})
+
+		assert.Equal(t, 1, d.Len(), "Expected the index to include the existing items")
+	})
+
+	t.Run("Removed items", func(t *testing.T) {
+		d := New()
+		d.AddItem(NewWarning("code1", "description1"))
+		other := New()
+		other.AddItem(NewError("code1", "description2"))
+
+		d.Merge(other, WithMergeStrategy(MergePreferHighestSeverity))
+		d.AddItem(NewWarning("code1", "description1"))
+
+		items := d.Snapshot()
+		assert.Equal(t, 2, len(items), "Expected the removed item not to be found")
+		assert.Equal(t, 1, items[1].Occurrences, "Expected the item to be added again")
+	})
+}
+
+func TestDedup_Occurrences(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := start
+	d := New(WithClock(func() time.Time { return now }))
+
+	d.AddWarning("warning1")
+	now = start.Add(time.Second)
+	d.AddWarning("warning1")
+	now = start.Add(2 * time.Second)
+	d.AddWarning("warning1")
+
+	item := d.Snapshot()[0]
+	assert.Equal(t, 3, item.Occurrences, "Expected every repeat to be counted")
+	assert.Equal(t, start, item.Timestamp, "Expected the timestamp to be the first seen time")
+	assert.Equal(t, start.Add(2*time.Second), item.LastSeen, "Expected the last seen time to be the last repeat")
+	assert.True(t, strings.Contains(d.String(), "warning1 [x3]"), "Expected the occurrences to be rendered")
+
+	t.Run("JSON", func(t *testing.T) {
+		b, err := json.Marshal(item)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		assert.Contains(t, string(b), `"occurrences":3,"lastSeen":"2024-01-02T03:04:07Z"`, "Expected the occurrences to be marshaled")
+
+		var result DiagnosticItem
+		assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+		assert.Equal(t, item.Occurrences, result.Occurrences, "Expected the occurrences to round trip")
+		assert.Equal(t, item.LastSeen, result.LastSeen, "Expected the last seen time to round trip")
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		other := New(WithClock(func() time.Time { return start.Add(time.Minute) }))
+		other.AddWarning("warning1")
+		other.AddWarning("warning1")
+
+		d.Merge(other)
+
+		item := d.Snapshot()[0]
+		assert.Equal(t, 5, item.Occurrences, "Expected the merged repeats to be counted")
+		assert.Equal(t, start.Add(time.Minute), item.LastSeen, "Expected the last seen time of the merged item")
+	})
+
+	t.Run("Same Item", func(t *testing.T) {
+		d := New(WithClock(func() time.Time { return now }))
+		item := NewWarning("code1", "warning1")
+
+		d.AddItem(item)
+		d.AddItem(item)
+		d.AddItem(item)
+
+		assert.Equal(t, 1, d.Len(), "Expected the item to be added once")
+		assert.Equal(t, 3, item.Occurrences, "Expected each add to count a single occurrence")
+	})
+}
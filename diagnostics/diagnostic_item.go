@@ -1,45 +1,145 @@
 package diagnostics
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
 
+// DiagnosticItem is first seen at Timestamp, its repeats are counted in
+// Occurrences and the last one is seen at LastSeen.
 type DiagnosticItem struct {
 	Code        string
 	Description string
 	Level       DiagnosticLevel
+	Scope       string
+	Attributes  []slog.Attr
+	Source      *SourceLocation
+	StackTrace  []SourceLocation
+	Timestamp   time.Time
+	Sequence    uint64
+	Elapsed     time.Duration
+	Occurrences int
+	LastSeen    time.Time
+	TraceID     string
+	Origin      string
+	Err         error `json:"-"`
+
+	// pc is the caller of the constructor, resolved into Source only when the
+	// item is added to a diagnostics capturing sources for its level.
+	pc uintptr
 }
 
 type DiagnosticLevel int
 
+// The levels are ordered by severity, Info is the zero value. The JSON
+// representation uses the level names so it does not depend on these values.
 const (
-	Info DiagnosticLevel = iota
+	Trace DiagnosticLevel = iota - 2
+	Debug
+	Info
+	Notice
 	Warning
 	Error
-	Trace
+	Critical
+	Fatal
 )
 
+var levelNames = map[DiagnosticLevel]string{
+	Trace:    "Trace",
+	Debug:    "Debug",
+	Info:     "Info",
+	Notice:   "Notice",
+	Warning:  "Warning",
+	Error:    "Error",
+	Critical: "Critical",
+	Fatal:    "Fatal",
+}
+
+// legacyLevels maps the numeric values used before the levels were ordered.
+var legacyLevels = map[int]DiagnosticLevel{
+	0: Info,
+	1: Warning,
+	2: Error,
+	3: Trace,
+}
+
+func ParseLevel(value string) (DiagnosticLevel, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(name, value) {
+			return level, nil
+		}
+	}
+
+	return Info, fmt.Errorf("unknown diagnostic level %q", value)
+}
+
+func (d DiagnosticLevel) IsValid() bool {
+	_, ok := levelNames[d]
+	return ok
+}
+
+func (d DiagnosticLevel) AtLeast(level DiagnosticLevel) bool {
+	return d >= level
+}
+
 func (d DiagnosticLevel) String() string {
-	return [...]string{"Info", "Warning", "Error", "Trace"}[d]
+	if name, ok := levelNames[d]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("DiagnosticLevel(%d)", int(d))
 }
 
 func (d DiagnosticLevel) MarshalJSON() ([]byte, error) {
+	if !d.IsValid() {
+		return nil, fmt.Errorf("unknown diagnostic level %d", int(d))
+	}
+
 	return []byte("\"" + d.String() + "\""), nil
 }
 
+// UnmarshalJSON accepts the level names and the legacy numeric values.
 func (d *DiagnosticLevel) UnmarshalJSON(b []byte) error {
-	switch string(b) {
-	case "\"Info\"":
-		*d = Info
-	case "\"Warning\"":
-		*d = Warning
-	case "\"Error\"":
-		*d = Error
-	case "\"Trace\"":
-		*d = Trace
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return err
+		}
+
+		*d = level
+		return nil
 	}
+
+	var value int
+	if err := json.Unmarshal(b, &value); err != nil {
+		return fmt.Errorf("invalid diagnostic level %s", b)
+	}
+
+	level, ok := legacyLevels[value]
+	if !ok {
+		return fmt.Errorf("unknown diagnostic level %d", value)
+	}
+
+	*d = level
 	return nil
 }
 
+// NewDiagnosticItem creates an item that records the location of its caller,
+// the location is only resolved into Source when the item is added to a
+// diagnostics capturing sources for its level.
 func NewDiagnosticItem(errorCode string, errorDescription string, errorLevel DiagnosticLevel) *DiagnosticItem {
+	item := newDiagnosticItem(errorCode, errorDescription, errorLevel)
+	item.pc = callerPC()
+	return item
+}
+
+func newDiagnosticItem(errorCode string, errorDescription string, errorLevel DiagnosticLevel) *DiagnosticItem {
 	return &DiagnosticItem{
 		Code:        errorCode,
 		Description: errorDescription,
@@ -48,19 +148,108 @@ func NewDiagnosticItem(errorCode string, errorDescription string, errorLevel Dia
 }
 
 func NewError(errorCode string, errorDescription string) *DiagnosticItem {
-	return NewDiagnosticItem(errorCode, errorDescription, Error)
+	item := newDiagnosticItem(errorCode, errorDescription, Error)
+	item.pc = callerPC()
+	return item
+}
+
+// NewErrorFrom creates an error item that keeps err so it can be matched
+// with errors.Is and errors.As.
+func NewErrorFrom(errorCode string, err error) *DiagnosticItem {
+	item := newErrorFrom(errorCode, err)
+	item.pc = callerPC()
+	return item
+}
+
+func newErrorFrom(errorCode string, err error) *DiagnosticItem {
+	item := newDiagnosticItem(errorCode, err.Error(), Error)
+	item.Err = err
+	return item
+}
+
+func NewCritical(errorCode string, errorDescription string) *DiagnosticItem {
+	item := newDiagnosticItem(errorCode, errorDescription, Critical)
+	item.pc = callerPC()
+	return item
+}
+
+func NewFatal(errorCode string, errorDescription string) *DiagnosticItem {
+	item := newDiagnosticItem(errorCode, errorDescription, Fatal)
+	item.pc = callerPC()
+	return item
 }
 
 func NewWarning(errorCode string, errorDescription string) *DiagnosticItem {
-	return NewDiagnosticItem(errorCode, errorDescription, Warning)
+	item := newDiagnosticItem(errorCode, errorDescription, Warning)
+	item.pc = callerPC()
+	return item
+}
+
+func NewNotice(errorCode string, errorDescription string) *DiagnosticItem {
+	item := newDiagnosticItem(errorCode, errorDescription, Notice)
+	item.pc = callerPC()
+	return item
 }
 
 func NewInfo(errorCode string, errorDescription string) *DiagnosticItem {
-	return NewDiagnosticItem(errorCode, errorDescription, Info)
+	item := newDiagnosticItem(errorCode, errorDescription, Info)
+	item.pc = callerPC()
+	return item
+}
+
+func NewDebug(errorCode string, errorDescription string) *DiagnosticItem {
+	item := newDiagnosticItem(errorCode, errorDescription, Debug)
+	item.pc = callerPC()
+	return item
 }
 
 func NewTrace(errorCode string, errorDescription string) *DiagnosticItem {
-	return NewDiagnosticItem(errorCode, errorDescription, Trace)
+	item := newDiagnosticItem(errorCode, errorDescription, Trace)
+	item.pc = callerPC()
+	return item
+}
+
+// With adds attributes to the item before it is added to a diagnostics, args
+// are either key value pairs or slog.Attr values like in slog.Logger.With.
+func (d *DiagnosticItem) With(args ...any) *DiagnosticItem {
+	d.Attributes = append(d.Attributes, slog.Group("", args...).Value.Group()...)
+	return d
+}
+
+func (d *DiagnosticItem) WithGroup(name string, args ...any) *DiagnosticItem {
+	d.Attributes = append(d.Attributes, slog.Group(name, args...))
+	return d
+}
+
+// Attribute returns the value of an attribute, the attributes of a group are
+// found using a dotted path like "request.method".
+func (d *DiagnosticItem) Attribute(path string) (slog.Value, bool) {
+	attrs := d.Attributes
+	keys := strings.Split(path, ".")
+	for index, key := range keys {
+		found := false
+		for _, attr := range attrs {
+			if attr.Key != key {
+				continue
+			}
+
+			value := attr.Value.Resolve()
+			if index == len(keys)-1 {
+				return value, true
+			}
+			if value.Kind() == slog.KindGroup {
+				attrs = value.Group()
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	return slog.Value{}, false
 }
 
 func (d *DiagnosticItem) String() string {
@@ -68,6 +257,98 @@ func (d *DiagnosticItem) String() string {
 	if d.Code != "" {
 		msg = fmt.Sprintf("[%v] %v: %v", d.Level, d.Code, d.Description)
 	}
+	if len(d.Attributes) > 0 {
+		msg = fmt.Sprintf("%v {%v}", msg, strings.Join(formatAttributes("", d.Attributes), " "))
+	}
+	if d.Occurrences > 1 {
+		msg = fmt.Sprintf("%v [x%v]", msg, d.Occurrences)
+	}
+	if d.Source != nil {
+		msg = fmt.Sprintf("%v (%v)", msg, d.Source)
+	}
 
 	return msg
 }
+
+// Error returns the description so the item reads like the error it wraps,
+// Format still prints the item with String.
+func (d *DiagnosticItem) Error() string {
+	return d.Description
+}
+
+// Format prints the item with String for the %v, %s and %q verbs, fmt would
+// use Error otherwise. The other verbs, %+v and %#v print the fields of the
+// item.
+func (d *DiagnosticItem) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && !f.Flag('#') && !f.Flag('+'), verb == 's':
+		io.WriteString(f, d.String())
+	case verb == 'q':
+		fmt.Fprintf(f, "%q", d.String())
+	default:
+		fmt.Fprintf(f, fmt.FormatString(f, verb), (*diagnosticItemFields)(d))
+	}
+}
+
+// diagnosticItemFields is a DiagnosticItem without its methods, fmt prints its
+// fields.
+type diagnosticItemFields DiagnosticItem
+
+func (d *DiagnosticItem) Unwrap() error {
+	return d.Err
+}
+
+func equalAttributes(attrs []slog.Attr, other []slog.Attr) bool {
+	if len(attrs) != len(other) {
+		return false
+	}
+
+	for index, attr := range attrs {
+		if !attr.Equal(other[index]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func formatAttributes(prefix string, attrs []slog.Attr) []string {
+	result := []string{}
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		if value.Kind() == slog.KindGroup {
+			result = append(result, formatAttributes(prefix+attr.Key+".", value.Group())...)
+			continue
+		}
+
+		text := value.String()
+		if text == "" || strings.ContainsAny(text, " =\"{}") {
+			text = strconv.Quote(text)
+		}
+		result = append(result, prefix+attr.Key+"="+text)
+	}
+
+	return result
+}
+
+func (d *DiagnosticItem) Clone() *DiagnosticItem {
+	if d == nil {
+		return nil
+	}
+
+	item := *d
+	if d.Attributes != nil {
+		item.Attributes = make([]slog.Attr, len(d.Attributes))
+		copy(item.Attributes, d.Attributes)
+	}
+	if d.Source != nil {
+		source := *d.Source
+		item.Source = &source
+	}
+	if d.StackTrace != nil {
+		item.StackTrace = make([]SourceLocation, len(d.StackTrace))
+		copy(item.StackTrace, d.StackTrace)
+	}
+
+	return &item
+}
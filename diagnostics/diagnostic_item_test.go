@@ -2,7 +2,13 @@ package diagnostics
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,6 +39,11 @@ func TestDiagnosticLevel_MarshalJSON(t *testing.T) {
 			level:    Trace,
 			expected: "\"Trace\"",
 		},
+		{
+			name:     "Critical",
+			level:    Critical,
+			expected: "\"Critical\"",
+		},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +53,11 @@ func TestDiagnosticLevel_MarshalJSON(t *testing.T) {
 			assert.Equal(t, []byte(tt.expected), b, "Unexpected marshaled JSON")
 		})
 	}
+
+	t.Run("Unknown level", func(t *testing.T) {
+		_, err := json.Marshal(DiagnosticLevel(42))
+		assert.Error(t, err, "Expected an error for an unknown level")
+	})
 }
 
 func TestDiagnosticLevel_UnmarshalJSON(t *testing.T) {
@@ -70,6 +86,31 @@ func TestDiagnosticLevel_UnmarshalJSON(t *testing.T) {
 			input:    "\"Trace\"",
 			expected: Trace,
 		},
+		{
+			name:     "Case insensitive",
+			input:    "\"notice\"",
+			expected: Notice,
+		},
+		{
+			name:     "Legacy Info",
+			input:    "0",
+			expected: Info,
+		},
+		{
+			name:     "Legacy Warning",
+			input:    "1",
+			expected: Warning,
+		},
+		{
+			name:     "Legacy Error",
+			input:    "2",
+			expected: Error,
+		},
+		{
+			name:     "Legacy Trace",
+			input:    "3",
+			expected: Trace,
+		},
 	}
 
 	for _, tt := range tests {
@@ -80,4 +121,172 @@ func TestDiagnosticLevel_UnmarshalJSON(t *testing.T) {
 			assert.Equal(t, tt.expected, level, "Unexpected unmarshaled value")
 		})
 	}
+
+	for _, input := range []string{"\"Unknown\"", "42", "true"} {
+		t.Run("Invalid "+input, func(t *testing.T) {
+			level := Warning
+			err := json.Unmarshal([]byte(input), &level)
+			assert.Error(t, err, "Expected an error for an unknown level")
+			assert.Equal(t, Warning, level, "Expected the level to be unchanged")
+		})
+	}
+}
+
+func TestDiagnosticLevel_Ordering(t *testing.T) {
+	levels := []DiagnosticLevel{Trace, Debug, Info, Notice, Warning, Error, Critical, Fatal}
+	for i := 1; i < len(levels); i++ {
+		assert.True(t, levels[i] > levels[i-1], "Expected %v to be more severe than %v", levels[i], levels[i-1])
+	}
+
+	assert.True(t, Critical.AtLeast(Error), "Expected Critical to be at least Error")
+	assert.True(t, Warning.AtLeast(Warning), "Expected Warning to be at least Warning")
+	assert.False(t, Trace.AtLeast(Warning), "Expected Trace not to be at least Warning")
+	assert.Equal(t, "DiagnosticLevel(42)", DiagnosticLevel(42).String(), "Expected unknown levels not to panic")
+	assert.False(t, DiagnosticLevel(42).IsValid(), "Expected unknown levels to be invalid")
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("FATAL")
+	assert.NoError(t, err, "Unexpected error while parsing")
+	assert.Equal(t, Fatal, level, "Expected level to be Fatal")
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err, "Expected an error for an unknown level")
+}
+
+func TestNewErrorFrom(t *testing.T) {
+	err := fmt.Errorf("reading file: %w", io.EOF)
+	item := NewErrorFrom("code1", err)
+
+	assert.Equal(t, "code1", item.Code, "Expected code to be 'code1'")
+	assert.Equal(t, err.Error(), item.Description, "Expected description to be the error message")
+	assert.Equal(t, Error, item.Level, "Expected level to be Error")
+	assert.Equal(t, err, item.Err, "Expected the original error to be kept")
+}
+
+func TestDiagnosticItem_Error(t *testing.T) {
+	t.Run("With original error", func(t *testing.T) {
+		item := NewErrorFrom("code1", io.EOF)
+
+		assert.Equal(t, io.EOF.Error(), item.Error(), "Expected the error message to be the description")
+		assert.Equal(t, io.EOF, item.Unwrap(), "Expected Unwrap to return the original error")
+		assert.True(t, errors.Is(item, io.EOF), "Expected errors.Is to match the original error")
+	})
+
+	t.Run("Without original error", func(t *testing.T) {
+		item := NewError("code1", "description1")
+
+		assert.Equal(t, "description1", item.Error(), "Expected the error message to be the description")
+		assert.Nil(t, item.Unwrap(), "Expected Unwrap to return nil")
+	})
+
+	t.Run("With typed error", func(t *testing.T) {
+		pathErr := &fs.PathError{Op: "open", Path: "file.txt", Err: fs.ErrNotExist}
+		item := NewErrorFrom("", pathErr)
+
+		var target *fs.PathError
+		assert.True(t, errors.As(item, &target), "Expected errors.As to find the typed error")
+		assert.Equal(t, pathErr, target, "Expected errors.As to return the original error")
+		assert.True(t, errors.Is(item, fs.ErrNotExist), "Expected errors.Is to match the wrapped error")
+	})
+}
+
+func TestDiagnosticItem_With(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := NewError("E100", "user update failed").
+		With("userId", 42, "name", "john doe", slog.Bool("admin", true)).
+		With("ratio", 0.5, "elapsed", 1500*time.Millisecond, "createdAt", createdAt).
+		WithGroup("request", "method", "GET", "path", "/users")
+
+	expected := []slog.Attr{
+		slog.Int("userId", 42),
+		slog.String("name", "john doe"),
+		slog.Bool("admin", true),
+		slog.Float64("ratio", 0.5),
+		slog.Duration("elapsed", 1500*time.Millisecond),
+		slog.Time("createdAt", createdAt),
+		slog.Group("request", slog.String("method", "GET"), slog.String("path", "/users")),
+	}
+	assert.Equal(t, len(expected), len(item.Attributes), "Expected all the attributes to be added")
+	for index, attr := range expected {
+		assert.True(t, attr.Equal(item.Attributes[index]), "Expected attribute %v to be %v, got %v", index, attr, item.Attributes[index])
+	}
+}
+
+func TestDiagnosticItem_Attribute(t *testing.T) {
+	item := NewInfo("", "description1").
+		With("userId", 42).
+		WithGroup("request", "method", "GET", slog.Group("headers", "accept", "application/json"))
+
+	tests := []struct {
+		name     string
+		path     string
+		expected any
+		found    bool
+	}{
+		{name: "Top level", path: "userId", expected: int64(42), found: true},
+		{name: "In group", path: "request.method", expected: "GET", found: true},
+		{name: "In nested group", path: "request.headers.accept", expected: "application/json", found: true},
+		{name: "Missing", path: "tenant", found: false},
+		{name: "Missing in group", path: "request.body", found: false},
+		{name: "Not a group", path: "userId.value", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, found := item.Attribute(tt.path)
+			assert.Equal(t, tt.found, found, "Unexpected found result")
+			if tt.found {
+				assert.Equal(t, tt.expected, value.Any(), "Unexpected attribute value")
+			}
+		})
+	}
+}
+
+func TestDiagnosticItem_Format(t *testing.T) {
+	item := &DiagnosticItem{Code: "code1", Description: "description1", Level: Error}
+
+	assert.Equal(t, "[Error] code1: description1", fmt.Sprintf("%v", item), "Expected %v to use the string representation")
+	assert.Equal(t, "[Error] code1: description1", fmt.Sprintf("%s", item), "Expected %s to use the string representation")
+	assert.Equal(t, `"[Error] code1: description1"`, fmt.Sprintf("%q", item), "Expected %q to quote the string representation")
+	assert.Equal(t, "[Error] code1: description1", fmt.Sprint(item), "Expected Sprint to use the string representation")
+	assert.Equal(t, "description1", item.Error(), "Expected Error to return the description")
+	assert.Contains(t, fmt.Sprintf("%#v", item), `Code:"code1"`, "Expected %#v to print the fields")
+	assert.Contains(t, fmt.Sprintf("%+v", item), "Code:code1", "Expected %+v to print the fields")
+	assert.Equal(t, "[Error] code1: description1", fmt.Sprintf("%-v", item), "Expected %v with flags to use the string representation")
+}
+
+func TestDiagnosticItem_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     *DiagnosticItem
+		expected string
+	}{
+		{
+			name:     "Without Code",
+			item:     &DiagnosticItem{Description: "description1", Level: Info},
+			expected: "[Info] description1",
+		},
+		{
+			name:     "With Code",
+			item:     &DiagnosticItem{Code: "code1", Description: "description1", Level: Error},
+			expected: "[Error] code1: description1",
+		},
+		{
+			name:     "With Attributes",
+			item:     (&DiagnosticItem{Code: "E100", Description: "x", Level: Error}).With("userId", 42, "name", "john doe").WithGroup("request", "method", "GET"),
+			expected: `[Error] E100: x {userId=42 name="john doe" request.method=GET}`,
+		},
+		{
+			name:     "With Source",
+			item:     &DiagnosticItem{Description: "description1", Level: Warning, Source: &SourceLocation{File: "/src/main.go", Line: 42}},
+			expected: "[Warning] description1 (main.go:42)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.item.String(), "Unexpected string representation")
+		})
+	}
 }
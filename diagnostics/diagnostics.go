@@ -2,160 +2,588 @@ package diagnostics
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Diagnostics struct {
-	traceID string
-	ctx     context.Context
-	stack   []*DiagnosticItem
+	mu           sync.RWMutex
+	config       config
+	traceID      string
+	traceContext *TraceContext
+	name         string
+	ctx          context.Context
+	metadata     []slog.Attr
+	createdAt    time.Time
+	sequence     *atomic.Uint64
+	parent       *Diagnostics
+	children     []*Diagnostics
+	stack        []*DiagnosticItem
+	index        map[string]*DiagnosticItem
+	indexed      int
+	levels       map[DiagnosticLevel]int
+	dropped      map[DiagnosticLevel]int
+	truncation   *DiagnosticItem
+	merging      *mergeIndex
+	closed       bool
 }
 
-func New() *Diagnostics {
-	traceId := uuid.New().String()
-	result := Diagnostics{
-		traceID: traceId,
-		ctx:     context.WithValue(context.Background(), TraceID, traceId),
-		stack:   []*DiagnosticItem{},
+// config holds the options of a diagnostics, children inherit it from their
+// parent.
+type config struct {
+	w3cTraceContext bool
+	sourceCapture   SourceCapture
+	stackTrace      bool
+	minLevel        *DiagnosticLevel
+	dedup           DedupPolicy
+	capacity        map[DiagnosticLevel]int
+	ringBuffer      bool
+	// onItem is called with the lock held for every item added, it must not
+	// use the diagnostics.
+	onItem func(item *DiagnosticItem)
+	clock  Clock
+}
+
+func New(options ...Option) *Diagnostics {
+	return NewFromContext(context.Background(), options...)
+}
+
+// FromContext returns the diagnostics attached to ctx with NewContext, the
+// options are ignored in that case. Otherwise it creates a new diagnostics as
+// NewFromContext does.
+func FromContext(ctx context.Context, options ...Option) *Diagnostics {
+	if d, ok := diagnosticsFromContext(ctx); ok {
+		return d
 	}
 
-	return &result
+	return NewFromContext(ctx, options...)
 }
 
-func FromContext(ctx context.Context) *Diagnostics {
-	traceId := uuid.New().String()
-	if ctx != nil {
-		if ctx.Value(TraceID) != nil {
-			if id, ok := ctx.Value(TraceID).(string); ok {
-				traceId = id
-			}
-		} else {
-			ctx = context.WithValue(ctx, TraceID, traceId)
+// NewContext returns a copy of ctx carrying d, the items added deep in the
+// call stack to the diagnostics returned by FromContext are added to d.
+func NewContext(ctx context.Context, d *Diagnostics) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, diagnosticsContextKey, d)
+}
+
+// NewFromContext creates a diagnostics reusing the trace id of ctx, if ctx
+// carries a W3C trace context a new span of that trace is used. A diagnostics
+// attached to ctx is ignored, the context of the new one carries it instead.
+func NewFromContext(ctx context.Context, options ...Option) *Diagnostics {
+	result := &Diagnostics{
+		sequence: &atomic.Uint64{},
+		stack:    []*DiagnosticItem{},
+	}
+	for _, option := range options {
+		option(result)
+	}
+	result.createdAt = result.now()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	traceId, _ := ctx.Value(TraceID).(string)
+	if parent, ok := TraceContextFromContext(ctx); ok {
+		traceContext := parent.NewSpan()
+		result.traceContext = &traceContext
+	} else if result.config.w3cTraceContext {
+		traceContext := NewTraceContext()
+		if id, ok := parseTraceID(traceId); ok {
+			traceContext.TraceID = id
 		}
+		result.traceContext = &traceContext
 	}
 
-	return &Diagnostics{
-		traceID: traceId,
-		ctx:     ctx,
-		stack:   []*DiagnosticItem{},
+	if result.traceContext != nil {
+		traceId = result.traceContext.TraceIDString()
+		ctx = ContextWithTraceContext(ctx, *result.traceContext)
+	}
+	if traceId == "" {
+		traceId = uuid.New().String()
+	}
+	if id, _ := ctx.Value(TraceID).(string); id != traceId {
+		ctx = context.WithValue(ctx, TraceID, traceId)
 	}
+
+	if len(result.metadata) > 0 {
+		result.metadata = mergeMetadata(MetadataFromContext(ctx), result.metadata)
+		ctx = context.WithValue(ctx, metadataContextKey, result.metadata)
+	} else {
+		result.metadata = MetadataFromContext(ctx)
+	}
+
+	result.traceID = traceId
+	result.ctx = NewContext(ctx, result)
+	return result
 }
 
-func (d *Diagnostics) AddInfo(info string) {
-	for _, i := range d.stack {
-		if strings.EqualFold(i.Code, "") && strings.EqualFold(i.Description, info) && i.Level == Info {
-			return
+func diagnosticsFromContext(ctx context.Context) (*Diagnostics, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	d, ok := ctx.Value(diagnosticsContextKey).(*Diagnostics)
+	return d, ok && d != nil
+}
+
+// Child creates a nested diagnostics for a sub operation, it shares the trace
+// id of its parent and its items are reported by the parent prefixed with the
+// scope of the child. The context of the child carries the child. Close the
+// child once the sub operation is done.
+func (d *Diagnostics) Child(name string) *Diagnostics {
+	d.mu.Lock()
+
+	child := &Diagnostics{
+		config:    d.config,
+		traceID:   d.traceID,
+		name:      name,
+		ctx:       d.ctx,
+		metadata:  d.metadata,
+		createdAt: d.createdAt,
+		parent:    d,
+		stack:     []*DiagnosticItem{},
+	}
+	if d.traceContext != nil {
+		traceContext := d.traceContext.NewSpan()
+		child.traceContext = &traceContext
+		child.ctx = ContextWithTraceContext(d.ctx, traceContext)
+	}
+	child.ctx = NewContext(child.ctx, child)
+
+	child.sequence = d.nextSequence()
+	if !d.closed {
+		d.children = append(d.children, child)
+		d.mu.Unlock()
+		return child
+	}
+
+	d.mu.Unlock()
+	d.parent.adopt(child)
+	return child
+}
+
+// adopt adds child to the children of d, or of its closest ancestor still
+// open.
+func (d *Diagnostics) adopt(child *Diagnostics) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		d.parent.adopt(child)
+		return
+	}
+	defer d.mu.Unlock()
+
+	d.children = append(d.children, child)
+}
+
+func (d *Diagnostics) Name() string {
+	return d.name
+}
+
+// Scope returns the path of names from the root diagnostics, for example
+// "import/parse".
+func (d *Diagnostics) Scope() string {
+	if d.parent == nil {
+		return d.name
+	}
+
+	parentScope := d.parent.Scope()
+	if parentScope == "" {
+		return d.name
+	}
+
+	return parentScope + "/" + d.name
+}
+
+func (d *Diagnostics) Parent() *Diagnostics {
+	return d.parent
+}
+
+// contains returns true when other is d or one of its descendants.
+func (d *Diagnostics) contains(other *Diagnostics) bool {
+	for ; other != nil; other = other.parent {
+		if other == d {
+			return true
 		}
 	}
 
-	d.stack = append(d.stack, NewInfo("", info))
+	return false
 }
 
-func (d *Diagnostics) AddWarning(warning string) {
-	for _, i := range d.stack {
-		if strings.EqualFold(i.Code, "") && strings.EqualFold(i.Description, warning) && i.Level == Warning {
+func (d *Diagnostics) Children() []*Diagnostics {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make([]*Diagnostics, len(d.children))
+	copy(result, d.children)
+	return result
+}
+
+// Close moves the items of d, and of its children, into its parent and
+// removes d from the children of the parent, so a long lived diagnostics does
+// not keep every finished sub operation. The items keep their scope and
+// sequence and the capacity of the parent applies to them. The items added to
+// d afterwards are added to the parent, with the scope of d, d only reports
+// the items it had when closed. Closing a root diagnostics does nothing.
+func (d *Diagnostics) Close() {
+	holder := d.parent
+	for holder != nil {
+		holder.mu.Lock()
+		if !holder.closed {
+			break
+		}
+		holder.mu.Unlock()
+		holder = holder.parent
+	}
+	if holder == nil {
+		return
+	}
+	defer holder.mu.Unlock()
+
+	for index, child := range holder.children {
+		if child == d {
+			holder.children = append(holder.children[:index], holder.children[index+1:]...)
+			holder.fold([]*Diagnostics{d})
 			return
 		}
 	}
+}
 
-	d.stack = append(d.stack, NewWarning("", warning))
+// Compact closes the children of d.
+func (d *Diagnostics) Compact() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	children := d.children
+	d.children = nil
+	d.fold(children)
 }
 
-func (d *Diagnostics) AddError(err error) {
-	for _, i := range d.stack {
-		if strings.EqualFold(i.Code, "") && strings.EqualFold(i.Description, err.Error()) && i.Level == Error {
-			return
+// fold moves the items of children into d, the lock of d must be held.
+func (d *Diagnostics) fold(children []*Diagnostics) {
+	if len(children) == 0 {
+		return
+	}
+
+	items := []*DiagnosticItem{}
+	dropped := map[DiagnosticLevel]int{}
+	for _, child := range children {
+		child.detach(&items, dropped)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Sequence < items[j].Sequence
+	})
+	stack := make([]*DiagnosticItem, 0, len(d.stack)+len(items))
+	for len(d.stack) > 0 && len(items) > 0 {
+		if d.stack[0].Sequence <= items[0].Sequence {
+			stack, d.stack = append(stack, d.stack[0]), d.stack[1:]
+		} else {
+			stack, items = append(stack, items[0]), items[1:]
 		}
 	}
+	d.stack = append(append(stack, d.stack...), items...)
+	d.index = nil
 
-	d.stack = append(d.stack, NewError("", err.Error()))
+	now := d.now()
+	for level, count := range dropped {
+		d.drop(level, count, now)
+	}
+	d.trim(now)
 }
 
-func (d *Diagnostics) AddErrorWithCode(code string, err error) {
+// detach closes d and its children and collects their items and dropped
+// counters, the lock of the diagnostics they are moved into must be held.
+func (d *Diagnostics) detach(items *[]*DiagnosticItem, dropped map[DiagnosticLevel]int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
 	for _, i := range d.stack {
-		if strings.EqualFold(i.Code, code) && strings.EqualFold(i.Description, err.Error()) && i.Level == Error {
-			return
+		if i != d.truncation {
+			*items = append(*items, i)
 		}
 	}
+	for level, count := range d.dropped {
+		dropped[level] += count
+	}
+	for _, child := range d.children {
+		child.detach(items, dropped)
+	}
+	d.children = nil
+}
 
-	d.stack = append(d.stack, NewError(code, err.Error()))
+func (d *Diagnostics) AddInfo(info string) {
+	d.AddItem(newDiagnosticItem("", info, Info))
+}
+
+func (d *Diagnostics) AddDebug(debug string) {
+	d.AddItem(newDiagnosticItem("", debug, Debug))
+}
+
+func (d *Diagnostics) AddNotice(notice string) {
+	d.AddItem(newDiagnosticItem("", notice, Notice))
+}
+
+func (d *Diagnostics) AddWarning(warning string) {
+	d.AddItem(newDiagnosticItem("", warning, Warning))
+}
+
+func (d *Diagnostics) AddError(err error) {
+	d.AddItem(newErrorFrom("", err))
+}
+
+func (d *Diagnostics) AddErrorWithCode(code string, err error) {
+	d.AddItem(newErrorFrom(code, err))
+}
+
+func (d *Diagnostics) AddCritical(err error) {
+	item := newErrorFrom("", err)
+	item.Level = Critical
+	d.AddItem(item)
 }
 
 func (d *Diagnostics) AddTrace(trace string) {
-	for _, i := range d.stack {
-		if strings.EqualFold(i.Code, "") && strings.EqualFold(i.Description, trace) && i.Level == Trace {
-			return
+	d.AddItem(newDiagnosticItem("", trace, Trace))
+}
+
+// AddItem takes ownership of diag, it should not be modified once added.
+func (d *Diagnostics) AddItem(diag *DiagnosticItem) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		if diag.Scope == "" {
+			diag.Scope = d.Scope()
 		}
+		d.parent.AddItem(diag)
+		return
 	}
+	defer d.mu.Unlock()
 
-	d.stack = append(d.stack, NewTrace("", trace))
+	d.addItem(diag)
 }
 
-func (d *Diagnostics) AddItem(diag *DiagnosticItem) {
-	for _, i := range d.stack {
-		if strings.EqualFold(i.Code, diag.Code) && strings.EqualFold(i.Description, diag.Description) && i.Level == diag.Level {
+func (d *Diagnostics) addItem(diag *DiagnosticItem) {
+	if d.config.minLevel != nil && !diag.Level.AtLeast(*d.config.minLevel) {
+		return
+	}
+	if diag.Scope == "" {
+		diag.Scope = d.Scope()
+	}
+
+	if i := d.findRepeat(diag); i != nil {
+		if i == diag {
+			// the same item added again counts a single occurrence
+			i.Occurrences++
+			i.LastSeen = d.now()
 			return
 		}
+		if diag.Timestamp.IsZero() {
+			diag.Timestamp = d.now()
+		}
+		i.repeat(diag)
+		return
+	}
+
+	if !d.config.sourceCapture.captures(diag.Level) {
+		diag.Source = nil
+	} else if diag.Source == nil && diag.pc != 0 {
+		diag.Source = sourceFromPC(diag.pc)
+	} else if diag.Source == nil {
+		diag.Source = callerSource()
+	}
+	if d.config.stackTrace && diag.Level.AtLeast(Error) && diag.StackTrace == nil {
+		diag.StackTrace = callerStack()
+	}
+
+	d.insertItem(diag)
+}
+
+// insertItem timestamps and sequences diag and adds it to the stack, it returns
+// false when diag is dropped. It must be called with the lock held.
+func (d *Diagnostics) insertItem(diag *DiagnosticItem) bool {
+	if diag.Timestamp.IsZero() {
+		diag.Timestamp = d.now()
+	}
+	if !d.reserve(diag) {
+		return false
+	}
+
+	if !d.createdAt.IsZero() {
+		diag.Elapsed = diag.Timestamp.Sub(d.createdAt)
+	}
+	if diag.Occurrences == 0 {
+		diag.Occurrences = 1
 	}
+	if diag.LastSeen.IsZero() {
+		diag.LastSeen = diag.Timestamp
+	}
+	diag.Sequence = d.nextSequence().Add(1)
 
 	d.stack = append(d.stack, diag)
+	if d.config.onItem != nil {
+		d.config.onItem(diag)
+	}
+	return true
 }
 
+func (d *Diagnostics) now() time.Time {
+	if d.config.clock != nil {
+		return d.config.clock()
+	}
+
+	return time.Now()
+}
+
+// nextSequence returns the sequence counter shared by the diagnostics tree, it
+// must be called with the lock held.
+func (d *Diagnostics) nextSequence() *atomic.Uint64 {
+	if d.sequence == nil {
+		d.sequence = &atomic.Uint64{}
+	}
+
+	return d.sequence
+}
+
+// Append merges diagnostic into d skipping the items already in d.
 func (d *Diagnostics) Append(diagnostic *Diagnostics) {
+	d.Merge(diagnostic)
+}
+
+// visit calls fn for every item of d and of its children, depth first, until
+// fn returns false. fn is called with the read lock held and must not modify
+// the diagnostics.
+func (d *Diagnostics) visit(fn func(item *DiagnosticItem) bool) bool {
+	d.mu.RLock()
 	for _, i := range d.stack {
-		for _, j := range diagnostic.stack {
-			if strings.EqualFold(i.Code, j.Code) && strings.EqualFold(i.Description, j.Description) && i.Level == j.Level {
-				return
-			}
+		if !fn(i) {
+			d.mu.RUnlock()
+			return false
+		}
+	}
+	children := d.children
+	d.mu.RUnlock()
 
-			d.stack = append(d.stack, j)
+	for _, child := range children {
+		if !child.visit(fn) {
+			return false
 		}
 	}
+
+	return true
+}
+
+// Snapshot returns a copy of the items of d and of its children in the order
+// they were added, changes to it or to its items do not affect the
+// diagnostics.
+func (d *Diagnostics) Snapshot() []*DiagnosticItem {
+	result := []*DiagnosticItem{}
+	d.visit(func(i *DiagnosticItem) bool {
+		result = append(result, i.Clone())
+		return true
+	})
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Sequence < result[j].Sequence
+	})
+	return result
+}
+
+func (d *Diagnostics) Len() int {
+	result := 0
+	d.visit(func(i *DiagnosticItem) bool {
+		result++
+		return true
+	})
+
+	return result
 }
 
 func (d *Diagnostics) GetDiagnostics() []*DiagnosticItem {
-	return d.stack
+	return d.Snapshot()
 }
 
 func (d *Diagnostics) GetTraceID() string {
 	return d.traceID
 }
 
-func (d *Diagnostics) Context() context.Context {
-	return d.ctx
+func (d *Diagnostics) TraceContext() (TraceContext, bool) {
+	if d.traceContext == nil {
+		return TraceContext{}, false
+	}
+
+	return *d.traceContext, true
 }
 
-func (d *Diagnostics) HasErrors() bool {
-	for _, i := range d.stack {
-		if i.Level == Error {
-			return true
-		}
+// TraceParent returns the W3C traceparent of the diagnostics span, it is empty
+// when the diagnostics does not use a W3C trace context.
+func (d *Diagnostics) TraceParent() string {
+	if d.traceContext == nil {
+		return ""
 	}
 
-	return false
+	return d.traceContext.TraceParent()
 }
 
-func (d *Diagnostics) HasWarnings() bool {
-	for _, i := range d.stack {
-		if i.Level == Warning {
-			return true
-		}
+func (d *Diagnostics) TraceState() string {
+	if d.traceContext == nil {
+		return ""
 	}
 
-	return false
+	return d.traceContext.State
+}
+
+func (d *Diagnostics) SpanID() string {
+	if d.traceContext == nil {
+		return ""
+	}
+
+	return d.traceContext.SpanIDString()
+}
+
+func (d *Diagnostics) CreatedAt() time.Time {
+	return d.createdAt
+}
+
+func (d *Diagnostics) Context() context.Context {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.ctx
+}
+
+func (d *Diagnostics) HasErrors() bool {
+	return !d.visit(func(i *DiagnosticItem) bool {
+		return !i.Level.AtLeast(Error)
+	})
+}
+
+func (d *Diagnostics) HasWarnings() bool {
+	return !d.visit(func(i *DiagnosticItem) bool {
+		return i.Level != Warning
+	})
 }
 
 func (d *Diagnostics) Errors() []error {
 	result := []error{}
-	for _, i := range d.stack {
-		if i.Level == Error {
+	for i := range d.All() {
+		if i.Level.AtLeast(Error) {
 			if i.Code == "" {
-				result = append(result, fmt.Errorf("error: %v", i.Description))
+				result = append(result, &itemError{message: "error: " + i.Description, item: i})
 			} else {
-				result = append(result, fmt.Errorf("error %v: %v", i.Code, i.Description))
+				result = append(result, &itemError{message: fmt.Sprintf("error %v: %v", i.Code, i.Description), item: i})
 			}
 		}
 	}
@@ -163,15 +591,72 @@ func (d *Diagnostics) Errors() []error {
 	return result
 }
 
+// Err returns nil when there are no errors, otherwise it returns a
+// *DiagnosticsError holding the errors of the diagnostics at the time of the call, it unwraps to
+// each one of them. The diagnostics can be recovered from the returned error
+// with errors.As.
+func (d *Diagnostics) Err() error {
+	errs := d.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &DiagnosticsError{Diagnostics: d, errs: errs}
+}
+
+// FromError returns the diagnostics of a *DiagnosticsError returned by Err or
+// by Group.Wait, err can wrap it.
+func FromError(err error) (*Diagnostics, bool) {
+	var target *DiagnosticsError
+	if !errors.As(err, &target) {
+		return nil, false
+	}
+
+	return target.Diagnostics, true
+}
+
+// DiagnosticsError is the error returned by Err, its message and the errors it unwraps to
+// are the ones of Diagnostics when it was returned.
+type DiagnosticsError struct {
+	Diagnostics *Diagnostics
+	errs        []error
+}
+
+func (e *DiagnosticsError) Error() string {
+	messages := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (e *DiagnosticsError) Unwrap() []error {
+	return e.errs
+}
+
+// itemError wraps an item with its message in Errors, fmt.Errorf would print
+// the item with Format instead of its description.
+type itemError struct {
+	message string
+	item    *DiagnosticItem
+}
+
+func (e *itemError) Error() string {
+	return e.message
+}
+
+func (e *itemError) Unwrap() error {
+	return e.item
+}
+
 func (d *Diagnostics) Warnings() []string {
 	result := []string{}
-	for _, i := range d.stack {
-		if i.Level == Warning {
-			if i.Code == "" {
-				result = append(result, fmt.Sprintf("warning: %v", i.Description))
-			} else {
-				result = append(result, fmt.Sprintf("warning %v: %v", i.Code, i.Description))
-			}
+	for i := range d.ByLevel(Warning) {
+		if i.Code == "" {
+			result = append(result, fmt.Sprintf("warning: %v", i.Description))
+		} else {
+			result = append(result, fmt.Sprintf("warning %v: %v", i.Code, i.Description))
 		}
 	}
 
@@ -180,10 +665,8 @@ func (d *Diagnostics) Warnings() []string {
 
 func (d *Diagnostics) Info() []string {
 	result := []string{}
-	for _, i := range d.stack {
-		if i.Level == Info {
-			result = append(result, fmt.Sprintf("%v", i.Description))
-		}
+	for i := range d.ByLevel(Info) {
+		result = append(result, fmt.Sprintf("%v", i.Description))
 	}
 
 	return result
@@ -191,28 +674,33 @@ func (d *Diagnostics) Info() []string {
 
 func (d *Diagnostics) Trace() []string {
 	result := []string{}
-	for _, i := range d.stack {
-		if i.Level == Trace {
-			result = append(result, fmt.Sprintf("trace: %v", i.Description))
-		}
+	for i := range d.ByLevel(Trace) {
+		result = append(result, fmt.Sprintf("trace: %v", i.Description))
 	}
 
 	return result
 }
 
 func (d *Diagnostics) Stack() []*DiagnosticItem {
-	return d.stack
+	return d.Snapshot()
 }
 
 func (d *Diagnostics) String() string {
-	result := ""
-	for _, i := range d.stack {
-		if d.traceID != "" {
-			result = fmt.Sprintf("%v[%v]%v\n", result, d.traceID, i.String())
-		} else {
-			result = fmt.Sprintf("%v%v\n", result, i.String())
+	var result strings.Builder
+	metadata := formatMetadata(d.Metadata())
+	for _, i := range d.Snapshot() {
+		if i.TraceID != "" {
+			result.WriteString("[" + i.TraceID + "]")
+		} else if d.traceID != "" {
+			result.WriteString("[" + d.traceID + "]")
+		}
+		result.WriteString(metadata)
+		if i.Scope != "" {
+			result.WriteString("[" + i.Scope + "]")
 		}
+		result.WriteString(i.String() + "\n")
+		writeStackTrace(&result, i.StackTrace)
 	}
 
-	return result
+	return result.String()
 }
@@ -0,0 +1,324 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+type diagnosticsJSON struct {
+	Version     int                `json:"version,omitempty"`
+	TraceID     string             `json:"traceId,omitempty"`
+	TraceParent string             `json:"traceParent,omitempty"`
+	TraceState  string             `json:"traceState,omitempty"`
+	CreatedAt   *time.Time         `json:"createdAt,omitempty"`
+	Name        string             `json:"name,omitempty"`
+	Metadata    map[string]any     `json:"metadata,omitempty"`
+	Items       []*DiagnosticItem  `json:"items"`
+	Children    []*diagnosticsJSON `json:"children,omitempty"`
+}
+
+type diagnosticItemJSON struct {
+	Code        string           `json:"code,omitempty"`
+	Description string           `json:"description"`
+	Level       DiagnosticLevel  `json:"level"`
+	Scope       string           `json:"scope,omitempty"`
+	Attributes  map[string]any   `json:"attributes,omitempty"`
+	Source      *SourceLocation  `json:"source,omitempty"`
+	StackTrace  []SourceLocation `json:"stackTrace,omitempty"`
+	Timestamp   *time.Time       `json:"timestamp,omitempty"`
+	Sequence    uint64           `json:"sequence,omitempty"`
+	Elapsed     string           `json:"elapsed,omitempty"`
+	Occurrences int              `json:"occurrences,omitempty"`
+	LastSeen    *time.Time       `json:"lastSeen,omitempty"`
+	TraceID     string           `json:"traceId,omitempty"`
+	Origin      string           `json:"origin,omitempty"`
+}
+
+func (d DiagnosticItem) MarshalJSON() ([]byte, error) {
+	item := diagnosticItemJSON{
+		Code:        d.Code,
+		Description: d.Description,
+		Level:       d.Level,
+		Scope:       d.Scope,
+		Attributes:  attributesToJSON(d.Attributes),
+		Source:      d.Source,
+		StackTrace:  d.StackTrace,
+		Timestamp:   timeToJSON(d.Timestamp),
+		Sequence:    d.Sequence,
+		TraceID:     d.TraceID,
+		Origin:      d.Origin,
+	}
+	if d.Elapsed != 0 {
+		item.Elapsed = d.Elapsed.String()
+	}
+	if d.Occurrences > 1 {
+		item.Occurrences = d.Occurrences
+	}
+	if !d.LastSeen.Equal(d.Timestamp) {
+		item.LastSeen = timeToJSON(d.LastSeen)
+	}
+
+	return json.Marshal(item)
+}
+
+func (d *DiagnosticItem) UnmarshalJSON(b []byte) error {
+	var item diagnosticItemJSON
+	if err := json.Unmarshal(b, &item); err != nil {
+		return err
+	}
+
+	*d = DiagnosticItem{
+		Code:        item.Code,
+		Description: item.Description,
+		Level:       item.Level,
+		Scope:       item.Scope,
+		Attributes:  attributesFromJSON(item.Attributes),
+		Source:      item.Source,
+		StackTrace:  item.StackTrace,
+		Sequence:    item.Sequence,
+		Occurrences: max(item.Occurrences, 1),
+		TraceID:     item.TraceID,
+		Origin:      item.Origin,
+	}
+	if item.Timestamp != nil {
+		d.Timestamp = *item.Timestamp
+	}
+	d.LastSeen = d.Timestamp
+	if item.LastSeen != nil {
+		d.LastSeen = *item.LastSeen
+	}
+	if item.Elapsed != "" {
+		elapsed, err := time.ParseDuration(item.Elapsed)
+		if err != nil {
+			return err
+		}
+		d.Elapsed = elapsed
+	}
+
+	return nil
+}
+
+func (d *Diagnostics) MarshalJSON() ([]byte, error) {
+	result := d.toJSON(nil)
+	result.Version = SchemaVersion
+	result.TraceID = d.traceID
+	result.TraceState = d.TraceState()
+	result.CreatedAt = timeToJSON(d.createdAt)
+
+	return json.Marshal(result)
+}
+
+// toJSON only includes the metadata of a child when it differs from the
+// metadata of its parent.
+func (d *Diagnostics) toJSON(parentMetadata []slog.Attr) *diagnosticsJSON {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := &diagnosticsJSON{
+		TraceParent: d.TraceParent(),
+		Name:        d.name,
+		Items:       make([]*DiagnosticItem, 0, len(d.stack)),
+	}
+	if !equalAttributes(d.metadata, parentMetadata) {
+		result.Metadata = attributesToJSON(d.metadata)
+	}
+	for _, i := range d.stack {
+		result.Items = append(result.Items, i.Clone())
+	}
+	for _, child := range d.children {
+		result.Children = append(result.Children, child.toJSON(d.metadata))
+	}
+
+	return result
+}
+
+func (d *Diagnostics) UnmarshalJSON(b []byte) error {
+	var value diagnosticsJSON
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	if value.Version > SchemaVersion {
+		return fmt.Errorf("unsupported diagnostics schema version %v", value.Version)
+	}
+
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if value.TraceID != "" {
+		ctx = context.WithValue(ctx, TraceID, value.TraceID)
+	}
+
+	d.sequence = &atomic.Uint64{}
+	return d.fromJSON(&value, &value, ctx)
+}
+
+func (d *Diagnostics) fromJSON(value *diagnosticsJSON, root *diagnosticsJSON, ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.traceID = root.TraceID
+	d.traceContext = nil
+	d.config.w3cTraceContext = false
+	if value.TraceParent != "" {
+		traceContext, err := ParseTraceContext(value.TraceParent, root.TraceState)
+		if err != nil {
+			return err
+		}
+
+		d.traceContext = &traceContext
+		d.config.w3cTraceContext = true
+		ctx = ContextWithTraceContext(ctx, traceContext)
+	}
+
+	if value.Metadata != nil {
+		d.metadata = attributesFromJSON(value.Metadata)
+		ctx = context.WithValue(ctx, metadataContextKey, d.metadata)
+	} else {
+		d.metadata = MetadataFromContext(ctx)
+	}
+
+	d.name = value.Name
+	d.ctx = NewContext(ctx, d)
+	d.createdAt = time.Time{}
+	if root.CreatedAt != nil {
+		d.createdAt = *root.CreatedAt
+	}
+
+	d.stack = []*DiagnosticItem{}
+	d.index = nil
+	d.dropped = nil
+	d.truncation = nil
+	for _, i := range value.Items {
+		if i != nil {
+			d.restoreTruncation(i)
+			d.stack = append(d.stack, i)
+			if i.Sequence > d.sequence.Load() {
+				d.sequence.Store(i.Sequence)
+			}
+		}
+	}
+
+	d.children = nil
+	for _, c := range value.Children {
+		child := &Diagnostics{parent: d, sequence: d.sequence}
+		if err := child.fromJSON(c, root, ctx); err != nil {
+			return err
+		}
+		d.children = append(d.children, child)
+	}
+
+	return nil
+}
+
+func timeToJSON(value time.Time) *time.Time {
+	if value.IsZero() {
+		return nil
+	}
+
+	return &value
+}
+
+func attributesToJSON(attrs []slog.Attr) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	result := make(map[string]any, len(attrs))
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		switch value.Kind() {
+		case slog.KindGroup:
+			result[attr.Key] = attributesToJSON(value.Group())
+		case slog.KindDuration:
+			result[attr.Key] = typedJSON{Type: "duration", Value: value.Duration().String()}
+		case slog.KindTime:
+			result[attr.Key] = typedJSON{Type: "time", Value: value.Time().Format(time.RFC3339Nano)}
+		case slog.KindAny:
+			if err, ok := value.Any().(error); ok {
+				result[attr.Key] = err.Error()
+			} else {
+				result[attr.Key] = value.Any()
+			}
+		default:
+			result[attr.Key] = value.Any()
+		}
+	}
+
+	return result
+}
+
+func attributesFromJSON(values map[string]any) []slog.Attr {
+	if len(values) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]slog.Attr, 0, len(values))
+	for _, key := range keys {
+		switch value := values[key].(type) {
+		case string:
+			result = append(result, slog.String(key, value))
+		case bool:
+			result = append(result, slog.Bool(key, value))
+		case float64:
+			if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+				result = append(result, slog.Int64(key, int64(value)))
+			} else {
+				result = append(result, slog.Float64(key, value))
+			}
+		case map[string]any:
+			if attr, ok := typedFromJSON(key, value); ok {
+				result = append(result, attr)
+			} else {
+				result = append(result, slog.Attr{Key: key, Value: slog.GroupValue(attributesFromJSON(value)...)})
+			}
+		default:
+			result = append(result, slog.Any(key, value))
+		}
+	}
+
+	return result
+}
+
+// typedJSON keeps the kind of the attributes JSON has no type for, so they are
+// not read back as strings. The keys start with a "$" so they do not collide
+// with the keys of a group.
+type typedJSON struct {
+	Type  string `json:"$type"`
+	Value string `json:"$value"`
+}
+
+// typedFromJSON reads an attribute written as a typedJSON.
+func typedFromJSON(key string, values map[string]any) (slog.Attr, bool) {
+	kind, _ := values["$type"].(string)
+	text, ok := values["$value"].(string)
+	if len(values) != 2 || !ok {
+		return slog.Attr{}, false
+	}
+
+	switch kind {
+	case "duration":
+		if value, err := time.ParseDuration(text); err == nil {
+			return slog.Duration(key, value), true
+		}
+	case "time":
+		if value, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			return slog.Time(key, value), true
+		}
+	}
+
+	return slog.Attr{}, false
+}
@@ -0,0 +1,204 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticItem_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     *DiagnosticItem
+		expected string
+	}{
+		{
+			name:     "With Code",
+			item:     &DiagnosticItem{Code: "code1", Description: "description1", Level: Error},
+			expected: `{"code":"code1","description":"description1","level":"Error"}`,
+		},
+		{
+			name:     "Without Code",
+			item:     &DiagnosticItem{Description: "description1", Level: Info},
+			expected: `{"description":"description1","level":"Info"}`,
+		},
+		{
+			name:     "With Scope",
+			item:     &DiagnosticItem{Description: "description1", Level: Warning, Scope: "import/parse"},
+			expected: `{"description":"description1","level":"Warning","scope":"import/parse"}`,
+		},
+		{
+			name: "With Attributes",
+			item: &DiagnosticItem{Description: "description1", Level: Info, Attributes: []slog.Attr{
+				slog.String("user", "john"),
+				slog.Int("id", 42),
+				slog.Duration("elapsed", 1500*time.Millisecond),
+				slog.Group("request", slog.String("method", "GET")),
+			}},
+			expected: `{"description":"description1","level":"Info","attributes":{"elapsed":{"$type":"duration","$value":"1.5s"},"id":42,"request":{"method":"GET"},"user":"john"}}`,
+		},
+		{
+			name:     "With Trace ID and Origin",
+			item:     &DiagnosticItem{Description: "description1", Level: Info, TraceID: "trace1", Origin: "trace1"},
+			expected: `{"description":"description1","level":"Info","traceId":"trace1","origin":"trace1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.item)
+			assert.NoError(t, err, "Unexpected error while marshaling")
+			assert.Equal(t, tt.expected, string(b), "Unexpected marshaled JSON")
+		})
+	}
+}
+
+func TestDiagnosticItem_UnmarshalJSON(t *testing.T) {
+	var item DiagnosticItem
+	err := json.Unmarshal([]byte(`{"code":"code1","description":"description1","level":"Warning","scope":"import"}`), &item)
+
+	assert.NoError(t, err, "Unexpected error while unmarshaling")
+	assert.Equal(t, "code1", item.Code, "Expected code to be 'code1'")
+	assert.Equal(t, "description1", item.Description, "Expected description to be 'description1'")
+	assert.Equal(t, Warning, item.Level, "Expected level to be Warning")
+	assert.Equal(t, "import", item.Scope, "Expected scope to be 'import'")
+}
+
+func TestDiagnosticItem_UnmarshalJSON_Attributes(t *testing.T) {
+	var item DiagnosticItem
+	err := json.Unmarshal([]byte(`{"description":"description1","level":"Info","attributes":{"user":"john","id":42,"ratio":0.5,"ok":true,"request":{"method":"GET"}}}`), &item)
+
+	expected := []slog.Attr{
+		slog.Int64("id", 42),
+		slog.Bool("ok", true),
+		slog.Float64("ratio", 0.5),
+		slog.Group("request", slog.String("method", "GET")),
+		slog.String("user", "john"),
+	}
+	assert.NoError(t, err, "Unexpected error while unmarshaling")
+	assert.Equal(t, expected, item.Attributes, "Expected the attributes to be unmarshaled")
+}
+
+func TestDiagnosticItem_UnmarshalJSON_TypedAttributes(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	item := &DiagnosticItem{Description: "description1", Level: Info, Attributes: []slog.Attr{
+		slog.Duration("elapsed", 1500*time.Millisecond),
+		slog.Group("request", slog.Time("at", at)),
+		slog.Group("typed", slog.String("type", "duration"), slog.String("value", "5s")),
+	}}
+
+	b, err := json.Marshal(item)
+	assert.NoError(t, err, "Unexpected error while marshaling")
+
+	var result DiagnosticItem
+	assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+	assert.Equal(t, item.Attributes, result.Attributes, "Expected the durations and times to round trip")
+}
+
+func TestDiagnostics_MarshalJSON(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		d := New(WithClock(clock))
+		d.traceID = "test-trace-id"
+
+		b, err := json.Marshal(d)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		assert.Equal(t, `{"version":2,"traceId":"test-trace-id","createdAt":"2024-01-02T03:04:06Z","items":[]}`, string(b), "Unexpected marshaled JSON")
+	})
+
+	t.Run("With Items and Children", func(t *testing.T) {
+		d := New(WithClock(clock))
+		d.traceID = "test-trace-id"
+		d.AddInfo("description1")
+		d.Child("import").AddErrorWithCode("code2", errors.New("description2"))
+
+		b, err := json.Marshal(d)
+		expected := `{"version":2,"traceId":"test-trace-id","createdAt":"2024-01-02T03:04:07Z",` +
+			`"items":[{"description":"description1","level":"Info","timestamp":"2024-01-02T03:04:08Z","sequence":1,"elapsed":"1s"}],` +
+			`"children":[{"name":"import","items":[{"code":"code2","description":"description2","level":"Error","scope":"import","timestamp":"2024-01-02T03:04:09Z","sequence":2,"elapsed":"2s"}]}]}`
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		assert.Equal(t, expected, string(b), "Unexpected marshaled JSON")
+	})
+}
+
+func TestDiagnostics_UnmarshalJSON(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		d := New()
+		d.AddInfo("description1")
+		d.AddWarning("description2")
+		child := d.Child("import")
+		child.Child("parse").AddErrorWithCode("code3", errors.New("description3"))
+
+		b, err := json.Marshal(d)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+
+		var result Diagnostics
+		err = json.Unmarshal(b, &result)
+		assert.NoError(t, err, "Unexpected error while unmarshaling")
+
+		assert.Equal(t, d.GetTraceID(), result.GetTraceID(), "Expected the trace id to be kept")
+		assert.Equal(t, d.GetTraceID(), result.Context().Value(TraceID), "Expected the context to carry the trace id")
+		assert.Same(t, &result, FromContext(result.Context()), "Expected the context to carry the diagnostics")
+		assert.Equal(t, d.String(), result.String(), "Expected the same string representation")
+		assert.True(t, result.HasErrors(), "Expected the diagnostics to have errors")
+		assert.Equal(t, 1, len(result.Children()), "Expected the diagnostics to have 1 child")
+		assert.Equal(t, "import/parse", result.Children()[0].Children()[0].Scope(), "Expected the grand child scope to be 'import/parse'")
+	})
+
+	t.Run("Round trip with W3C trace context", func(t *testing.T) {
+		parent, _ := ParseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=value")
+		d := FromContext(ContextWithTraceContext(context.Background(), parent))
+		child := d.Child("import")
+		child.AddInfo("description1")
+
+		b, err := json.Marshal(d)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+
+		var result Diagnostics
+		err = json.Unmarshal(b, &result)
+		assert.NoError(t, err, "Unexpected error while unmarshaling")
+
+		assert.Equal(t, d.TraceParent(), result.TraceParent(), "Expected the traceparent to be kept")
+		assert.Equal(t, "vendor=value", result.TraceState(), "Expected the tracestate to be kept")
+		assert.Equal(t, child.SpanID(), result.Children()[0].SpanID(), "Expected the child span to be kept")
+	})
+
+	t.Run("Invalid traceparent", func(t *testing.T) {
+		var result Diagnostics
+		err := json.Unmarshal([]byte(`{"version":2,"traceId":"test-trace-id","traceParent":"invalid","items":[]}`), &result)
+
+		assert.Error(t, err, "Expected an error for an invalid traceparent")
+	})
+
+	t.Run("Unsupported version", func(t *testing.T) {
+		var result Diagnostics
+		err := json.Unmarshal([]byte(`{"version":3,"traceId":"test-trace-id","items":[]}`), &result)
+
+		assert.Error(t, err, "Expected an error for an unsupported version")
+	})
+
+	t.Run("Previous version", func(t *testing.T) {
+		var result Diagnostics
+		err := json.Unmarshal([]byte(`{"version":1,"traceId":"test-trace-id","items":[{"description":"description1","level":"Info","attributes":{"elapsed":"1.5s"}}]}`), &result)
+
+		assert.NoError(t, err, "Expected the previous version to be read")
+		assert.Equal(t, []slog.Attr{slog.String("elapsed", "1.5s")}, result.Snapshot()[0].Attributes, "Expected the attributes to be read as written")
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		var result Diagnostics
+		err := json.Unmarshal([]byte(`{"items":"invalid"}`), &result)
+
+		assert.Error(t, err, "Expected an error for invalid JSON")
+	})
+}
@@ -2,9 +2,13 @@ package diagnostics
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,7 +38,8 @@ func TestFromContext(t *testing.T) {
 		d := FromContext(ctx)
 
 		assert.Equalf(t, "existing-trace-id", d.traceID, "Expected traceID to be 'existing-trace-id', got '%s'", d.traceID)
-		assert.Equalf(t, ctx, d.ctx, "Expected ctx to be the same as the input context, got different")
+		assert.Equalf(t, "existing-trace-id", d.ctx.Value(TraceID), "Expected ctx to carry the values of the input context")
+		assert.Same(t, d, FromContext(d.ctx), "Expected ctx to carry the diagnostics")
 		assert.Equalf(t, 0, len(d.stack), "Expected stack to be empty, got %d items", len(d.stack))
 	})
 
@@ -46,6 +51,246 @@ func TestFromContext(t *testing.T) {
 		assert.Equalf(t, d.traceID, contextTraceId, "Expected traceID to be the same as the context value, got different")
 		assert.Equalf(t, 0, len(d.stack), "Expected stack to be empty, got %d items", len(d.stack))
 	})
+
+	t.Run("With attached Diagnostics", func(t *testing.T) {
+		d := New()
+		ctx := NewContext(context.Background(), d)
+
+		result := FromContext(ctx, WithW3CTraceContext())
+
+		assert.Same(t, d, result, "Expected the attached diagnostics to be returned")
+		assert.Equal(t, "", result.TraceParent(), "Expected the options to be ignored")
+	})
+
+	t.Run("Items added deep in the call stack", func(t *testing.T) {
+		d := New()
+		load := func(ctx context.Context) {
+			FromContext(ctx).AddWarning("warning1")
+		}
+
+		load(d.Context())
+
+		assert.Equal(t, []string{"warning: warning1"}, d.Warnings(), "Expected the items to be added to the attached diagnostics")
+	})
+}
+
+func TestNewFromContext(t *testing.T) {
+	d := New()
+	ctx := NewContext(d.Context(), d)
+
+	result := NewFromContext(ctx)
+
+	assert.NotSame(t, d, result, "Expected a new diagnostics")
+	assert.Equal(t, d.GetTraceID(), result.GetTraceID(), "Expected the trace id of the context to be reused")
+	assert.Same(t, result, FromContext(result.Context()), "Expected the context to carry the new diagnostics")
+}
+
+func TestNewContext(t *testing.T) {
+	d := New()
+
+	ctx := NewContext(context.Background(), d)
+
+	result, ok := diagnosticsFromContext(ctx)
+	assert.True(t, ok, "Expected the diagnostics to be attached")
+	assert.Same(t, d, result, "Expected the diagnostics to be attached")
+}
+
+func TestChild(t *testing.T) {
+	t.Run("Child shares the parent trace id", func(t *testing.T) {
+		d := New()
+		child := d.Child("import")
+
+		assert.Equal(t, d.traceID, child.GetTraceID(), "Expected the child to share the parent trace id")
+		assert.Equal(t, d.traceID, child.Context().Value(TraceID), "Expected the child context to derive from the parent context")
+		assert.Same(t, child, FromContext(child.Context()), "Expected the child context to carry the child")
+		assert.Equal(t, d, child.Parent(), "Expected the child parent to be the diagnostics")
+		assert.Equal(t, []*Diagnostics{child}, d.Children(), "Expected the diagnostics to have the child")
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		d := New()
+		child := d.Child("import")
+		grandChild := child.Child("parse")
+
+		assert.Equal(t, "", d.Scope(), "Expected the root scope to be empty")
+		assert.Equal(t, "import", child.Scope(), "Expected the child scope to be 'import'")
+		assert.Equal(t, "import/parse", grandChild.Scope(), "Expected the grand child scope to be 'import/parse'")
+		assert.Equal(t, "parse", grandChild.Name(), "Expected the grand child name to be 'parse'")
+	})
+
+	t.Run("Items roll up into the parent", func(t *testing.T) {
+		d := New()
+		d.traceID = "test-trace-id"
+		child := d.Child("import")
+		grandChild := child.Child("parse")
+
+		d.AddInfo("description1")
+		child.AddWarning("description2")
+		grandChild.AddErrorWithCode("code3", errors.New("description3"))
+
+		assert.Equal(t, 1, len(child.stack), "Expected the child to hold its own items")
+		assert.Equal(t, 3, d.Len(), "Expected the parent to report all items")
+		assert.Equal(t, 2, child.Len(), "Expected the child to report its items and the grand child items")
+		assert.True(t, d.HasErrors(), "Expected the parent to have errors")
+		assert.True(t, child.HasErrors(), "Expected the child to have errors")
+		assert.False(t, grandChild.HasWarnings(), "Expected the grand child to have no warnings")
+		assert.Equal(t, 1, len(d.Errors()), "Expected the parent to report the grand child error")
+		assert.Equal(t, "error code3: description3", d.Errors()[0].Error(), "Expected the error message to be 'error code3: description3'")
+
+		expected := "[test-trace-id][Info] description1\n[test-trace-id][import][Warning] description2\n[test-trace-id][import/parse][Error] code3: description3\n"
+		assert.Equal(t, expected, d.String(), "Expected the string representation to include the scope")
+	})
+
+	t.Run("Same item in different scopes", func(t *testing.T) {
+		d := New()
+		child := d.Child("import")
+
+		d.AddInfo("info1")
+		child.AddInfo("info1")
+		child.AddInfo("info1")
+
+		assert.Equal(t, 2, d.Len(), "Expected the same item to be kept once per scope")
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("Folds the child into the parent", func(t *testing.T) {
+		d := New()
+		for i := 0; i < 100; i++ {
+			job := d.Child(fmt.Sprintf("job%d", i))
+			job.AddInfo("started")
+			job.Child("step").AddWarning("slow")
+			job.Close()
+		}
+
+		assert.Empty(t, d.Children(), "Expected the closed children to be removed")
+		assert.Equal(t, 200, d.Len(), "Expected the items of the closed children to be kept")
+		assert.Equal(t, "job99/step", d.Snapshot()[199].Scope, "Expected the items to keep their scope")
+	})
+
+	t.Run("Use after close", func(t *testing.T) {
+		d := New()
+		child := d.Child("import")
+		grandChild := child.Child("parse")
+		child.AddInfo("info1")
+		child.Close()
+		child.Close()
+
+		child.AddInfo("info2")
+		grandChild.AddInfo("info3")
+		child.Child("load").AddInfo("info4")
+		other := New()
+		other.AddInfo("info5")
+		child.Merge(other)
+
+		items := d.Snapshot()
+		assert.Equal(t, 5, len(items), "Expected the items added after close to reach the parent")
+		assert.Equal(t, "import", items[1].Scope, "Expected the item to keep the scope of the closed child")
+		assert.Equal(t, "import/parse", items[2].Scope, "Expected the item to keep the scope of the closed grand child")
+		assert.Equal(t, "import/load", items[3].Scope, "Expected the new child to keep its scope")
+		assert.Equal(t, "import", items[4].Scope, "Expected the merged item to get the scope of the closed child")
+		assert.Equal(t, 1, len(d.Children()), "Expected the new child to be adopted by the parent")
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+		d.Close()
+		d.AddInfo("info2")
+
+		assert.Equal(t, 2, d.Len(), "Expected closing a root to do nothing")
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		d := New()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				child := d.Child(fmt.Sprintf("job%d", i))
+				for j := 0; j < 10; j++ {
+					child.AddInfo(fmt.Sprintf("info%d", j))
+				}
+				child.Close()
+				child.AddInfo("done")
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 110, d.Len(), "Expected every item to be kept")
+		assert.Empty(t, d.Children(), "Expected the closed children to be removed")
+	})
+}
+
+func TestCompact(t *testing.T) {
+	d := New()
+	d.AddInfo("info1")
+	child := d.Child("import")
+	child.AddWarning("warning1")
+	d.AddInfo("info2")
+	child.Child("parse").AddErrorWithCode("code1", errors.New("error1"))
+	expected := d.String()
+
+	d.Compact()
+
+	assert.Empty(t, d.Children(), "Expected the children to be removed")
+	assert.Equal(t, 4, len(d.stack), "Expected the items of the children to be moved")
+	assert.Equal(t, expected, d.String(), "Expected the items to keep their order and scope")
+	assert.Equal(t, "import/parse", d.Snapshot()[3].Scope, "Expected the grand child scope to be kept")
+
+	d.AddInfo("info1")
+	d.AddWarning("warning1")
+
+	assert.Equal(t, 5, d.Len(), "Expected the items to be deduplicated per scope after compacting")
+	assert.Equal(t, 2, d.Snapshot()[0].Occurrences, "Expected the repeat to be counted")
+}
+
+func TestTimeline(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := start
+	clock := func() time.Time {
+		return now
+	}
+
+	d := New(WithClock(clock))
+	assert.Equal(t, start, d.CreatedAt(), "Expected the creation time to come from the clock")
+
+	now = start.Add(time.Second)
+	d.AddInfo("info1")
+	child := d.Child("import")
+	now = start.Add(2 * time.Second)
+	child.AddWarning("warning1")
+	now = start.Add(3 * time.Second)
+	d.AddError(errors.New("error1"))
+	d.AddInfo("info1")
+
+	items := d.GetDiagnostics()
+	assert.Equal(t, 3, len(items), "Expected 3 items")
+	assert.Equal(t, []string{"info1", "warning1", "error1"}, []string{items[0].Description, items[1].Description, items[2].Description}, "Expected the items in the order they were added")
+	assert.Equal(t, []uint64{1, 2, 3}, []uint64{items[0].Sequence, items[1].Sequence, items[2].Sequence}, "Expected the sequence to be shared with the children")
+	assert.Equal(t, start.Add(2*time.Second), items[1].Timestamp, "Expected the timestamp to come from the clock")
+	assert.Equal(t, 3*time.Second, items[2].Elapsed, "Expected the elapsed time since the creation")
+	assert.Equal(t, start, child.CreatedAt(), "Expected the child to share the creation time")
+}
+
+func TestTimeline_JSON(t *testing.T) {
+	d := New()
+	d.AddInfo("info1")
+	d.Child("import").AddInfo("info2")
+
+	b, err := json.Marshal(d)
+	assert.NoError(t, err, "Unexpected error while marshaling")
+
+	var result Diagnostics
+	assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+	result.AddInfo("info3")
+	result.Children()[0].AddInfo("info4")
+
+	items := result.GetDiagnostics()
+	assert.Equal(t, d.CreatedAt().UnixNano(), result.CreatedAt().UnixNano(), "Expected the creation time to be kept")
+	assert.Equal(t, d.GetDiagnostics()[1].Timestamp.UnixNano(), items[1].Timestamp.UnixNano(), "Expected the timestamps to be kept")
+	assert.Equal(t, []uint64{1, 2, 3, 4}, []uint64{items[0].Sequence, items[1].Sequence, items[2].Sequence, items[3].Sequence}, "Expected the sequence to continue after the unmarshaled items")
 }
 
 func TestAddInfo(t *testing.T) {
@@ -162,6 +407,18 @@ func TestAddTrace(t *testing.T) {
 	assert.Equal(t, Trace, d.stack[1].Level, "Expected level to be Trace")
 }
 
+func TestAddItem_Attributes(t *testing.T) {
+	d := New()
+
+	d.AddItem(NewError("E100", "user update failed").With("userId", 1))
+	d.AddItem(NewError("E100", "user update failed").With("userId", 1))
+	d.AddItem(NewError("E100", "user update failed").With("userId", 2))
+	d.AddItem(NewError("E100", "user update failed"))
+
+	assert.Equal(t, 3, d.Len(), "Expected items with different attributes to be kept")
+	assert.Contains(t, d.String(), "[Error] E100: user update failed {userId=2}\n", "Expected the attributes in the string representation")
+}
+
 func TestAddItem(t *testing.T) {
 	t.Run("AddItem with new Item", func(t *testing.T) {
 		d := New()
@@ -213,8 +470,10 @@ func TestAddItem(t *testing.T) {
 		// Verify that the stack has 1 item
 		assert.Equal(t, 1, len(d.stack), "Expected stack to have 1 item after appending")
 
-		// Verify that the appended item is the same as the diagnostic item
-		assert.Equal(t, diag, d.stack[0], "Expected the appended item to be the same as the diagnostic item")
+		// Verify that the existing item counts the repeat
+		assert.Equal(t, diag.Code, d.stack[0].Code, "Expected the existing item to be kept")
+		assert.Equal(t, 2, d.stack[0].Occurrences, "Expected the repeat to be counted")
+		assert.Equal(t, diag.Timestamp, d.stack[0].LastSeen, "Expected the repeat to be the last seen")
 	})
 }
 
@@ -245,6 +504,71 @@ func TestGetDiagnostics(t *testing.T) {
 	assert.Equal(t, d.stack, stack, "Expected the returned stack to be the same as the internal stack")
 }
 
+func TestSnapshot(t *testing.T) {
+	d := New()
+	d.AddInfo("info1")
+	d.AddWarning("warning1")
+
+	snapshot := d.Snapshot()
+	assert.Equal(t, 2, len(snapshot), "Expected the snapshot to have 2 items")
+
+	// Changing the snapshot should not change the diagnostics
+	snapshot[0].Description = "changed"
+	snapshot = append(snapshot, NewError("code1", "error1"))
+
+	assert.Equal(t, 3, len(snapshot), "Expected the snapshot to have 3 items")
+	assert.Equal(t, 2, d.Len(), "Expected the diagnostics to still have 2 items")
+	assert.Equal(t, "info1", d.stack[0].Description, "Expected the description to still be 'info1'")
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	d := New()
+	workers := 10
+	itemsPerWorker := 100
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < itemsPerWorker; i++ {
+				d.AddInfo(fmt.Sprintf("info %d-%d", worker, i))
+				d.AddWarning(fmt.Sprintf("warning %d-%d", worker, i))
+				d.AddError(fmt.Errorf("error %d-%d", worker, i))
+				d.AddInfo("shared info")
+
+				_ = d.HasErrors()
+				_ = d.Len()
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_ = d.Errors()
+			_ = d.Warnings()
+			_ = d.GetDiagnostics()
+			_ = d.String()
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		other := New()
+		other.AddTrace("trace1")
+		for i := 0; i < 10; i++ {
+			other.Append(d)
+		}
+	}()
+
+	wg.Wait()
+
+	assert.Equal(t, workers*itemsPerWorker*3+1, d.Len(), "Expected no items to be lost")
+}
+
 func TestGetTraceID(t *testing.T) {
 	d := New()
 	d.traceID = "test-trace-id"
@@ -302,6 +626,30 @@ func TestHasErrors(t *testing.T) {
 
 		assert.True(t, hasErrors, "Expected HasErrors to return true")
 	})
+
+	t.Run("With Critical", func(t *testing.T) {
+		d := New()
+		d.AddCritical(io.EOF)
+
+		assert.True(t, d.HasErrors(), "Expected Critical items to be errors")
+		assert.Equal(t, 1, len(d.Errors()), "Expected Critical items to be returned by Errors")
+	})
+}
+
+func TestMinLevel(t *testing.T) {
+	d := New(WithMinLevel(Warning))
+	d.AddTrace("trace1")
+	d.AddDebug("debug1")
+	d.AddInfo("info1")
+	d.AddNotice("notice1")
+	d.AddWarning("warning1")
+	d.AddError(io.EOF)
+
+	assert.Equal(t, 2, d.Len(), "Expected the items below Warning to be dropped")
+
+	child := d.Child("import")
+	child.AddInfo("info2")
+	assert.Equal(t, 0, child.Len(), "Expected children to inherit the minimum level")
 }
 
 func TestHasWarnings(t *testing.T) {
@@ -407,6 +755,100 @@ func TestErrors(t *testing.T) {
 		assert.Equal(t, 1, len(errors), "Expected the returned errors to have 1 item")
 		assert.Equal(t, "error: description3", errors[0].Error(), "Expected the error message to be 'error: description3'")
 	})
+
+	t.Run("With Errors wrapping the original error", func(t *testing.T) {
+		d := New()
+		d.AddError(io.EOF)
+		d.AddErrorWithCode("code2", fmt.Errorf("reading: %w", io.ErrUnexpectedEOF))
+
+		errs := d.Errors()
+		assert.Equal(t, 2, len(errs), "Expected the returned errors to have 2 items")
+		assert.Equal(t, "error: EOF", errs[0].Error(), "Expected the error message to be 'error: EOF'")
+		assert.True(t, errors.Is(errs[0], io.EOF), "Expected errors.Is to match io.EOF")
+		assert.True(t, errors.Is(errs[1], io.ErrUnexpectedEOF), "Expected errors.Is to match io.ErrUnexpectedEOF")
+
+		var item *DiagnosticItem
+		assert.True(t, errors.As(errs[1], &item), "Expected errors.As to return the diagnostic item")
+		assert.Equal(t, "code2", item.Code, "Expected the item code to be 'code2'")
+	})
+}
+
+func TestErr(t *testing.T) {
+	t.Run("Without Errors", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+		d.AddWarning("warning1")
+
+		assert.Nil(t, d.Err(), "Expected Err to return nil")
+	})
+
+	t.Run("With Errors", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+		d.AddError(io.EOF)
+		d.Child("import").AddErrorWithCode("code2", errors.New("description2"))
+
+		err := d.Err()
+		assert.Error(t, err, "Expected Err to return an error")
+		assert.Equal(t, "error: EOF\nerror code2: description2", err.Error(), "Expected the error message to join all errors")
+		assert.True(t, errors.Is(err, io.EOF), "Expected errors.Is to match io.EOF")
+
+		unwrapped, ok := err.(interface{ Unwrap() []error })
+		assert.True(t, ok, "Expected the error to implement Unwrap() []error")
+		assert.Equal(t, 2, len(unwrapped.Unwrap()), "Expected the error to unwrap to 2 errors")
+	})
+
+	t.Run("Snapshot of the errors", func(t *testing.T) {
+		d := New()
+		d.AddErrorWithCode("code1", errors.New("a"))
+		err := d.Err()
+
+		d.AddErrorWithCode("code2", errors.New("b"))
+
+		assert.Equal(t, "error code1: a", err.Error(), "Expected the error not to change once returned")
+		assert.Equal(t, 2, len(d.Errors()), "Expected the diagnostics to keep the new error")
+	})
+
+	t.Run("Formatting the diagnostics", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+		d.AddWarning("warning1")
+
+		assert.Equal(t, d.String(), fmt.Sprint(d), "Expected the diagnostics to be printed with String")
+	})
+
+	t.Run("Without diagnostics", func(t *testing.T) {
+		_, ok := FromError(io.EOF)
+
+		assert.False(t, ok, "Expected FromError to find no diagnostics")
+	})
+
+	t.Run("Recover the diagnostics with FromError", func(t *testing.T) {
+		d := New()
+		d.AddErrorWithCode("code1", errors.New("description1"))
+
+		err := fmt.Errorf("running step: %w", d.Err())
+
+		target, ok := FromError(err)
+		assert.True(t, ok, "Expected FromError to find the diagnostics")
+		assert.Same(t, d, target, "Expected FromError to return the same diagnostics")
+
+		var item *DiagnosticItem
+		assert.True(t, errors.As(err, &item), "Expected errors.As to find the diagnostic item")
+		assert.Equal(t, "code1", item.Code, "Expected the item code to be 'code1'")
+	})
+
+	t.Run("Recover the diagnostics with errors.As", func(t *testing.T) {
+		d := New()
+		d.AddErrorWithCode("code1", errors.New("description1"))
+
+		err := fmt.Errorf("running step: %w", d.Err())
+
+		var target *DiagnosticsError
+		assert.True(t, errors.As(err, &target), "Expected errors.As to find the diagnostics error")
+		assert.Same(t, d, target.Diagnostics, "Expected the error to hold the same diagnostics")
+		assert.Equal(t, "error code1: description1", target.Error(), "Expected the error message of the snapshot")
+	})
 }
 
 func TestWarnings(t *testing.T) {
@@ -678,8 +1120,22 @@ func TestAppend(t *testing.T) {
 		// Verify that the stack has 2 items
 		assert.Equal(t, 2, len(d.stack), "Expected stack to have 2 items after appending")
 
-		// Verify that the appended item is the same as the diagnostic item
-		assert.Equal(t, diagItem, d.stack[1], "Expected the appended item to be the same as the diagnostic item")
+		// Verify that the appended item is a copy of the diagnostic item
+		assert.NotSame(t, diagItem, d.stack[1], "Expected the appended item to be a copy")
+		assert.Equal(t, diagItem.Code, d.stack[1].Code, "Expected the appended item to have the same code")
+		assert.Equal(t, diagItem.Description, d.stack[1].Description, "Expected the appended item to have the same description")
+		assert.Equal(t, diagItem.Level, d.stack[1].Level, "Expected the appended item to have the same level")
+	})
+
+	t.Run("Append to empty stack", func(t *testing.T) {
+		d := New()
+		other := New()
+		other.AddInfo("info1")
+		other.AddWarning("warning1")
+
+		d.Append(other)
+
+		assert.Equal(t, 2, d.Len(), "Expected every item to be appended")
 	})
 
 	t.Run("Append with existing Item", func(t *testing.T) {
@@ -709,7 +1165,73 @@ func TestAppend(t *testing.T) {
 		// Verify that the stack still has 1 item
 		assert.Equal(t, 1, len(d.stack), "Expected stack to still have 1 item after appending")
 
-		// Verify that the appended item is the same as the diagnostic item
-		assert.Equal(t, diag.stack[0], d.stack[0], "Expected the appended item to be the same as the diagnostic item")
+		// Verify that the existing item counts the repeat
+		assert.Equal(t, diag.stack[0].Code, d.stack[0].Code, "Expected the existing item to be kept")
+		assert.Equal(t, 2, d.stack[0].Occurrences, "Expected the repeat to be counted")
 	})
 }
+
+func BenchmarkAddItem(b *testing.B) {
+	d := New()
+	for i := 0; i < b.N; i++ {
+		d.AddItem(NewInfo("", fmt.Sprintf("info %d", i%1000)))
+	}
+}
+
+func BenchmarkAddItemParallel(b *testing.B) {
+	d := New()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			d.AddItem(NewInfo("", fmt.Sprintf("info %d", i%1000)))
+			i++
+		}
+	})
+}
+
+// BenchmarkAddItem_Unique reports the cost per item of filling a diagnostics
+// with unique items, it stays flat as the number of items grows.
+func BenchmarkAddItem_Unique(b *testing.B) {
+	for _, size := range []int{1000, 10000, 100000} {
+		descriptions := make([]string, size)
+		for i := range descriptions {
+			descriptions[i] = fmt.Sprintf("info %d", i)
+		}
+
+		b.Run(fmt.Sprintf("%d items", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				d := New()
+				for _, description := range descriptions {
+					d.AddItem(&DiagnosticItem{Description: description, Level: Info})
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*size), "ns/item")
+		})
+	}
+}
+
+func BenchmarkAddItem_Repeated(b *testing.B) {
+	for _, size := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("%d items", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				d := New()
+				for j := 0; j < size; j++ {
+					d.AddItem(&DiagnosticItem{Code: "code1", Description: "repeated", Level: Warning})
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*size), "ns/item")
+		})
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	d := New()
+	for i := 0; i < 100; i++ {
+		d.AddInfo(fmt.Sprintf("info %d", i))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = d.String()
+	}
+}
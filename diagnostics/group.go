@@ -0,0 +1,132 @@
+package diagnostics
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Group runs goroutines that each get their own diagnostics sharing the trace
+// of the parent diagnostics, similar to errgroup.Group. The context of the
+// group is cancelled as soon as one of them adds an error.
+type Group struct {
+	parent  *Diagnostics
+	ctx     context.Context
+	cancel  context.CancelFunc
+	limit   chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	members []*Diagnostics
+}
+
+type GroupOption func(g *Group)
+
+// WithLimit limits the number of goroutines running at the same time, Go
+// blocks until one of them returns.
+func WithLimit(limit int) GroupOption {
+	return func(g *Group) {
+		if limit > 0 {
+			g.limit = make(chan struct{}, limit)
+		}
+	}
+}
+
+func NewGroup(parent *Diagnostics, options ...GroupOption) *Group {
+	ctx, cancel := context.WithCancel(parent.Context())
+	g := &Group{
+		parent: parent,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, option := range options {
+		option(g)
+	}
+
+	return g
+}
+
+// Context returns the context shared by the goroutines of the group, it is
+// cancelled on the first error or when Wait returns. The context of the
+// diagnostics of each goroutine is derived from it and carries that
+// diagnostics.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+func (g *Group) Go(fn func(d *Diagnostics)) {
+	d := g.newMember()
+
+	g.mu.Lock()
+	g.members = append(g.members, d)
+	g.mu.Unlock()
+
+	if g.limit != nil {
+		g.limit <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.limit != nil {
+			defer func() { <-g.limit }()
+		}
+
+		fn(d)
+	}()
+}
+
+// Wait waits for the goroutines of the group and merges their diagnostics
+// into the parent in the order they were started, it returns the errors added
+// by the goroutines, the errors the parent already had are not included.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	g.mu.Lock()
+	members := g.members
+	g.members = nil
+	g.mu.Unlock()
+
+	errs := []error{}
+	for _, member := range members {
+		g.parent.Merge(member)
+		errs = append(errs, member.Errors()...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &DiagnosticsError{Diagnostics: g.parent, errs: errs}
+}
+
+// newMember creates the diagnostics of a goroutine, its items get the scope of
+// the parent.
+func (g *Group) newMember() *Diagnostics {
+	parent := g.parent
+	parent.mu.RLock()
+	defer parent.mu.RUnlock()
+
+	d := &Diagnostics{
+		config:    parent.config,
+		traceID:   parent.traceID,
+		name:      parent.Scope(),
+		ctx:       g.ctx,
+		metadata:  parent.metadata,
+		createdAt: parent.createdAt,
+		sequence:  &atomic.Uint64{},
+		stack:     []*DiagnosticItem{},
+	}
+	d.config.onItem = func(item *DiagnosticItem) {
+		if item.Level.AtLeast(Error) {
+			g.cancel()
+		}
+	}
+	if parent.traceContext != nil {
+		traceContext := parent.traceContext.NewSpan()
+		d.traceContext = &traceContext
+		d.ctx = ContextWithTraceContext(g.ctx, traceContext)
+	}
+	d.ctx = NewContext(d.ctx, d)
+
+	return d
+}
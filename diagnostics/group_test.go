@@ -0,0 +1,151 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	t.Run("Merges in order", func(t *testing.T) {
+		parent := New(WithMetadata(TenantMetadata, "acme"))
+		parent.AddInfo("started")
+		g := NewGroup(parent)
+
+		release := make(chan struct{})
+		traceIDs := make(chan string, 3)
+		for i := 0; i < 3; i++ {
+			g.Go(func(d *Diagnostics) {
+				traceIDs <- d.GetTraceID()
+				if i == 0 {
+					<-release
+				}
+				d.AddInfo(fmt.Sprintf("worker%d", i))
+				d.AddWarning(fmt.Sprintf("warning%d", i))
+				if i == 2 {
+					close(release)
+				}
+			})
+		}
+
+		assert.NoError(t, g.Wait(), "Unexpected error")
+		close(traceIDs)
+		for traceID := range traceIDs {
+			assert.Equal(t, parent.GetTraceID(), traceID, "Expected the trace id of the parent")
+		}
+		assert.Equal(t, []string{"started", "worker0", "worker1", "worker2"}, parent.Info(), "Expected the items in the order the goroutines were started")
+		assert.Equal(t, 3, len(parent.Warnings()), "Expected every warning to be merged")
+	})
+
+	t.Run("Scope and metadata", func(t *testing.T) {
+		parent := New(WithMetadata(TenantMetadata, "acme")).Child("import")
+		g := NewGroup(parent)
+
+		g.Go(func(d *Diagnostics) {
+			assert.Same(t, d, FromContext(d.Context()), "Expected the context to carry the diagnostics")
+			assert.Equal(t, parent.Metadata(), d.Metadata(), "Expected the metadata of the parent")
+			d.AddInfo("info1")
+			d.Child("parse").AddInfo("info2")
+		})
+		assert.NoError(t, g.Wait(), "Unexpected error")
+
+		items := parent.Snapshot()
+		assert.Equal(t, "import", items[0].Scope, "Expected the scope of the parent")
+		assert.Equal(t, "import/parse", items[1].Scope, "Expected the scope of the parent")
+	})
+
+	t.Run("W3C trace context", func(t *testing.T) {
+		parent := New(WithW3CTraceContext())
+		g := NewGroup(parent)
+
+		var spanID string
+		g.Go(func(d *Diagnostics) {
+			spanID = d.SpanID()
+			traceContext, ok := TraceContextFromContext(d.Context())
+			assert.True(t, ok, "Expected the trace context in the context")
+			assert.Equal(t, d.SpanID(), traceContext.SpanIDString(), "Expected the span of the goroutine")
+		})
+		assert.NoError(t, g.Wait(), "Unexpected error")
+
+		assert.NotEqual(t, parent.SpanID(), spanID, "Expected a span per goroutine")
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		g := NewGroup(New(), WithLimit(2))
+
+		var running, peak atomic.Int32
+		for i := 0; i < 10; i++ {
+			g.Go(func(d *Diagnostics) {
+				current := running.Add(1)
+				for {
+					previous := peak.Load()
+					if current <= previous || peak.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+			})
+		}
+
+		assert.NoError(t, g.Wait(), "Unexpected error")
+		assert.LessOrEqual(t, peak.Load(), int32(2), "Expected at most 2 goroutines at the same time")
+	})
+
+	t.Run("Fail fast", func(t *testing.T) {
+		parent := New()
+		g := NewGroup(parent)
+
+		g.Go(func(d *Diagnostics) {
+			<-d.Context().Done()
+			d.AddWarning("cancelled")
+		})
+		g.Go(func(d *Diagnostics) {
+			d.Child("load").AddError(io.EOF)
+		})
+
+		err := g.Wait()
+		assert.Error(t, err, "Expected the error of the goroutine")
+		assert.True(t, errors.Is(err, io.EOF), "Expected the error of the goroutine")
+		assert.Equal(t, []string{"warning: cancelled"}, parent.Warnings(), "Expected the context to be cancelled")
+		assert.ErrorIs(t, g.Context().Err(), context.Canceled, "Expected the context of the group to be cancelled")
+	})
+
+	t.Run("Errors of the goroutines only", func(t *testing.T) {
+		parent := New()
+		parent.AddErrorWithCode("code1", errors.New("before the group"))
+		g := NewGroup(parent)
+
+		assert.NoError(t, g.Wait(), "Expected the errors added before the group not to be returned")
+
+		g = NewGroup(parent)
+		g.Go(func(d *Diagnostics) {
+			d.AddError(io.EOF)
+		})
+
+		err := g.Wait()
+		assert.Equal(t, "error: EOF", err.Error(), "Expected only the error of the goroutine")
+		result, ok := FromError(err)
+		assert.True(t, ok, "Expected the diagnostics to be found")
+		assert.Same(t, parent, result, "Expected the error to hold the parent")
+		assert.Equal(t, 2, len(parent.Errors()), "Expected the error of the goroutine to be merged")
+	})
+
+	t.Run("Parent context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		g := NewGroup(FromContext(ctx))
+
+		cancel()
+		g.Go(func(d *Diagnostics) {
+			<-d.Context().Done()
+		})
+
+		assert.NoError(t, g.Wait(), "Unexpected error")
+	})
+}
@@ -0,0 +1,147 @@
+package diagnostics
+
+import (
+	"container/heap"
+	"iter"
+)
+
+type entry struct {
+	owner *Diagnostics
+	item  *DiagnosticItem
+}
+
+// cursor holds the items of a diagnostics of the tree not reached yet, the
+// items of each diagnostics are ordered by sequence.
+type cursor struct {
+	owner *Diagnostics
+	items []*DiagnosticItem
+	order int
+}
+
+// cursors is a heap of the cursors of a tree ordered by the sequence of their
+// next item, the oldest first or the newest first when backward.
+type cursors struct {
+	list     []*cursor
+	backward bool
+}
+
+func (c *cursors) next(index int) *DiagnosticItem {
+	items := c.list[index].items
+	if c.backward {
+		return items[len(items)-1]
+	}
+
+	return items[0]
+}
+
+func (c *cursors) Len() int {
+	return len(c.list)
+}
+
+func (c *cursors) Less(i, j int) bool {
+	a, b := c.next(i), c.next(j)
+	if a.Sequence == b.Sequence {
+		return (c.list[i].order < c.list[j].order) != c.backward
+	}
+
+	return (a.Sequence < b.Sequence) != c.backward
+}
+
+func (c *cursors) Swap(i, j int) {
+	c.list[i], c.list[j] = c.list[j], c.list[i]
+}
+
+func (c *cursors) Push(x any) {
+	c.list = append(c.list, x.(*cursor))
+}
+
+func (c *cursors) Pop() any {
+	last := c.list[len(c.list)-1]
+	c.list = c.list[:len(c.list)-1]
+	return last
+}
+
+// entries iterates over the items of d and of its children in the order they
+// were added, or newest first when backward, without copying them. The items
+// of each diagnostics are merged by sequence instead of sorting all of them.
+func (d *Diagnostics) entries(backward bool) iter.Seq[entry] {
+	return func(yield func(entry) bool) {
+		c := &cursors{backward: backward}
+		d.collect(c)
+		heap.Init(c)
+
+		for c.Len() > 0 {
+			next := c.list[0]
+			if !yield(entry{owner: next.owner, item: c.next(0)}) {
+				return
+			}
+
+			if backward {
+				next.items = next.items[:len(next.items)-1]
+			} else {
+				next.items = next.items[1:]
+			}
+			if len(next.items) == 0 {
+				heap.Pop(c)
+			} else {
+				heap.Fix(c, 0)
+			}
+		}
+	}
+}
+
+func (d *Diagnostics) collect(c *cursors) {
+	d.mu.RLock()
+	if len(d.stack) > 0 {
+		items := make([]*DiagnosticItem, len(d.stack))
+		copy(items, d.stack)
+		c.list = append(c.list, &cursor{owner: d, items: items, order: len(c.list)})
+	}
+	children := d.children
+	d.mu.RUnlock()
+
+	for _, child := range children {
+		child.collect(c)
+	}
+}
+
+func (e entry) clone() *DiagnosticItem {
+	e.owner.mu.RLock()
+	defer e.owner.mu.RUnlock()
+
+	return e.item.Clone()
+}
+
+// All iterates over the items of d and of its children in the order they were
+// added. Each item is copied when it is reached, so changes to it do not
+// affect the diagnostics.
+func (d *Diagnostics) All() iter.Seq[*DiagnosticItem] {
+	return func(yield func(*DiagnosticItem) bool) {
+		for e := range d.entries(false) {
+			if !yield(e.clone()) {
+				return
+			}
+		}
+	}
+}
+
+func (d *Diagnostics) ByLevel(level DiagnosticLevel) iter.Seq[*DiagnosticItem] {
+	return func(yield func(*DiagnosticItem) bool) {
+		for e := range d.entries(false) {
+			if e.item.Level == level && !yield(e.clone()) {
+				return
+			}
+		}
+	}
+}
+
+// Backward iterates over the items of d and of its children, newest first.
+func (d *Diagnostics) Backward() iter.Seq[*DiagnosticItem] {
+	return func(yield func(*DiagnosticItem) bool) {
+		for e := range d.entries(true) {
+			if !yield(e.clone()) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	d := New()
+	d.AddInfo("info1")
+	d.Child("import").AddWarning("warning1")
+	d.AddError(io.EOF)
+
+	descriptions := []string{}
+	for i := range d.All() {
+		descriptions = append(descriptions, i.Description)
+	}
+	assert.Equal(t, []string{"info1", "warning1", "EOF"}, descriptions, "Expected the items in the order they were added")
+
+	t.Run("Break", func(t *testing.T) {
+		count := 0
+		for range d.All() {
+			count++
+			break
+		}
+
+		assert.Equal(t, 1, count, "Expected the iteration to stop")
+	})
+
+	t.Run("Items are copies", func(t *testing.T) {
+		for i := range d.All() {
+			i.Description = "changed"
+		}
+
+		assert.Equal(t, []string{"info1"}, d.Info(), "Expected the diagnostics to be unchanged")
+	})
+
+	t.Run("Interleaved children", func(t *testing.T) {
+		d := New()
+		children := []*Diagnostics{d.Child("a"), d.Child("b"), d.Child("a").Child("c")}
+		expected := []string{}
+		for i := 0; i < 12; i++ {
+			description := fmt.Sprintf("info%d", i)
+			children[(i*7)%len(children)].AddInfo(description)
+			expected = append(expected, description)
+		}
+
+		descriptions := []string{}
+		for i := range d.All() {
+			descriptions = append(descriptions, i.Description)
+		}
+		assert.Equal(t, expected, descriptions, "Expected the items of the children to be merged in the order they were added")
+
+		descriptions = []string{}
+		for i := range d.Backward() {
+			descriptions = append([]string{i.Description}, descriptions...)
+		}
+		assert.Equal(t, expected, descriptions, "Expected the items of the children to be merged newest first")
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		for range New().All() {
+			assert.Fail(t, "Expected no items")
+		}
+	})
+}
+
+func TestByLevel(t *testing.T) {
+	d := New()
+	d.AddWarning("warning1")
+	d.AddInfo("info1")
+	d.Child("import").AddWarning("warning2")
+
+	descriptions := []string{}
+	for i := range d.ByLevel(Warning) {
+		descriptions = append(descriptions, i.Description)
+	}
+
+	assert.Equal(t, []string{"warning1", "warning2"}, descriptions, "Expected only the warnings")
+}
+
+func TestBackward(t *testing.T) {
+	d := New()
+	d.AddInfo("info1")
+	d.Child("import").AddWarning("warning1")
+	d.AddInfo("info2")
+
+	descriptions := []string{}
+	for i := range d.Backward() {
+		descriptions = append(descriptions, i.Description)
+	}
+	assert.Equal(t, []string{"info2", "warning1", "info1"}, descriptions, "Expected the newest items first")
+
+	for i := range d.Backward() {
+		assert.Equal(t, "info2", i.Description, "Expected the newest item first")
+		break
+	}
+}
+
+func BenchmarkAll(b *testing.B) {
+	d := New()
+	for i := 0; i < 10000; i++ {
+		d.AddItem(&DiagnosticItem{Description: fmt.Sprintf("warning %d", i), Level: Warning})
+	}
+
+	b.Run("First", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for range d.ByLevel(Warning) {
+				break
+			}
+		}
+	})
+
+	b.Run("Warnings", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = d.Warnings()
+		}
+	})
+}
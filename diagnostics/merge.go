@@ -0,0 +1,196 @@
+package diagnostics
+
+type MergeStrategy int
+
+const (
+	// MergeDedup counts the items with the same code, description, level,
+	// scope and attributes as an item already in the diagnostics, ignoring
+	// case, as repeats whatever its dedup policy. This is the default strategy.
+	MergeDedup MergeStrategy = iota
+	// MergeKeepAll adds every item, repeats included.
+	MergeKeepAll
+	// MergeDedupByCode counts the items with a code already in the same scope
+	// of the diagnostics as repeats, items without a code are compared as with
+	// MergeDedup.
+	MergeDedupByCode
+	// MergePreferHighestSeverity keeps a single item per code and scope, the
+	// one with the highest level, it counts the occurrences of the items it
+	// replaces.
+	MergePreferHighestSeverity
+	// MergeDedupPolicy counts the repeats according to the dedup policy of the
+	// diagnostics, as when the items are added to it.
+	MergeDedupPolicy
+)
+
+type MergeOption func(o *mergeOptions)
+
+type mergeOptions struct {
+	strategy        MergeStrategy
+	preserveTraceID bool
+	recordOrigin    bool
+}
+
+func WithMergeStrategy(strategy MergeStrategy) MergeOption {
+	return func(o *mergeOptions) {
+		o.strategy = strategy
+	}
+}
+
+// PreserveTraceID keeps the trace id of the merged diagnostics on its items,
+// by default they are re-parented to the trace id of the receiver.
+func PreserveTraceID() MergeOption {
+	return func(o *mergeOptions) {
+		o.preserveTraceID = true
+	}
+}
+
+// RecordOrigin sets the origin of the merged items to the traceparent, or the
+// trace id, of the diagnostics they come from.
+func RecordOrigin() MergeOption {
+	return func(o *mergeOptions) {
+		o.recordOrigin = true
+	}
+}
+
+// Merge adds the items of other, and of its children, to d in the order they
+// were added to other. The items are copied and get a new sequence in d.
+// Merging d, one of its descendants or one of its ancestors does nothing, the
+// items would be reported twice by the tree.
+func (d *Diagnostics) Merge(other *Diagnostics, options ...MergeOption) {
+	if other == nil || d.contains(other) || other.contains(d) {
+		return
+	}
+
+	opts := mergeOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	items := other.Snapshot()
+	origin := other.TraceParent()
+	if origin == "" {
+		origin = other.traceID
+	}
+
+	for _, item := range items {
+		if opts.preserveTraceID && item.TraceID == "" && other.traceID != d.traceID {
+			item.TraceID = other.traceID
+		}
+		if opts.recordOrigin && item.Origin == "" {
+			item.Origin = origin
+		}
+		if item.Scope == "" {
+			item.Scope = d.Scope()
+		}
+	}
+
+	d.mergeItems(items, opts.strategy)
+}
+
+// mergeItems adds the merged items to d, or to its closest ancestor still open.
+func (d *Diagnostics) mergeItems(items []*DiagnosticItem, strategy MergeStrategy) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		d.parent.mergeItems(items, strategy)
+		return
+	}
+	defer d.mu.Unlock()
+
+	switch strategy {
+	case MergeKeepAll, MergeDedupPolicy:
+	case MergeDedupByCode, MergePreferHighestSeverity:
+		d.indexMerge(DedupByCode)
+		defer func() { d.merging = nil }()
+	default:
+		d.indexMerge(DedupCaseInsensitive)
+		defer func() { d.merging = nil }()
+	}
+
+	for _, item := range items {
+		d.mergeItem(item, strategy)
+	}
+}
+
+// mergeIndex holds the items of a diagnostics by key while merging into it.
+type mergeIndex struct {
+	policy DedupPolicy
+	items  map[string][]*DiagnosticItem
+}
+
+// indexMerge indexes the items of d with the keys of policy, it must be called
+// with the lock held.
+func (d *Diagnostics) indexMerge(policy DedupPolicy) {
+	d.merging = &mergeIndex{policy: policy, items: map[string][]*DiagnosticItem{}}
+	for _, i := range d.stack {
+		if i != d.truncation {
+			d.merging.add(i)
+		}
+	}
+}
+
+func (m *mergeIndex) add(item *DiagnosticItem) {
+	if key, ok := m.policy.Key(item); ok {
+		m.items[key] = append(m.items[key], item)
+	}
+}
+
+func (m *mergeIndex) remove(item *DiagnosticItem) {
+	key, ok := m.policy.Key(item)
+	if !ok {
+		return
+	}
+
+	for i, existing := range m.items[key] {
+		if existing == item {
+			m.items[key] = append(m.items[key][:i], m.items[key][i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *Diagnostics) mergeItem(item *DiagnosticItem, strategy MergeStrategy) {
+	if d.config.minLevel != nil && !item.Level.AtLeast(*d.config.minLevel) {
+		return
+	}
+
+	switch strategy {
+	case MergeKeepAll:
+	case MergeDedupPolicy:
+		if i := d.findRepeat(item); i != nil {
+			i.repeat(item)
+			return
+		}
+	default:
+		key, _ := d.merging.policy.Key(item)
+		existing := d.merging.items[key]
+		if len(existing) == 0 {
+			break
+		}
+
+		highest := existing[0]
+		for _, i := range existing[1:] {
+			if i.Level > highest.Level {
+				highest = i
+			}
+		}
+		if strategy != MergePreferHighestSeverity || item.Level <= highest.Level {
+			highest.repeat(item)
+			return
+		}
+
+		for _, i := range append([]*DiagnosticItem{}, existing...) {
+			item.repeat(i)
+			for index := range d.stack {
+				if d.stack[index] == i {
+					d.removeItem(index)
+					break
+				}
+			}
+		}
+	}
+
+	if d.insertItem(item) && d.merging != nil {
+		d.merging.add(item)
+	}
+}
@@ -0,0 +1,212 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	newOther := func() *Diagnostics {
+		other := New()
+		other.AddItem(NewWarning("code1", "description1"))
+		other.AddItem(NewError("code1", "description2"))
+		other.AddItem(NewInfo("code2", "description3"))
+		return other
+	}
+	descriptions := func(d *Diagnostics) []string {
+		result := []string{}
+		for _, i := range d.Snapshot() {
+			result = append(result, i.Description)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		strategy MergeStrategy
+		expected []string
+	}{
+		{
+			name:     "Dedup",
+			strategy: MergeDedup,
+			expected: []string{"description1", "description2", "description3"},
+		},
+		{
+			name:     "Keep All",
+			strategy: MergeKeepAll,
+			expected: []string{"description1", "description1", "description2", "description3"},
+		},
+		{
+			name:     "Dedup By Code",
+			strategy: MergeDedupByCode,
+			expected: []string{"description1", "description3"},
+		},
+		{
+			name:     "Prefer Highest Severity",
+			strategy: MergePreferHighestSeverity,
+			expected: []string{"description2", "description3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New()
+			d.AddItem(NewWarning("code1", "description1"))
+
+			d.Merge(newOther(), WithMergeStrategy(tt.strategy))
+
+			assert.Equal(t, tt.expected, descriptions(d), "Unexpected merged items")
+		})
+	}
+
+	t.Run("Dedup ignores the dedup policy", func(t *testing.T) {
+		d := New(WithDedupPolicy(DedupNone))
+		d.AddItem(NewWarning("code1", "description1"))
+		other := New()
+		other.AddItem(NewWarning("CODE1", "Description1"))
+		other.AddItem(NewWarning("code1", "description2"))
+
+		d.Merge(other)
+
+		assert.Equal(t, []string{"description1", "description2"}, descriptions(d), "Expected the items to be compared by code, description and level")
+		assert.Equal(t, 2, d.Snapshot()[0].Occurrences, "Expected the repeat to be counted")
+	})
+
+	t.Run("Dedup Policy", func(t *testing.T) {
+		d := New(WithDedupPolicy(DedupByCode))
+		d.AddItem(NewWarning("code1", "description1"))
+
+		d.Merge(newOther(), WithMergeStrategy(MergeDedupPolicy))
+
+		assert.Equal(t, []string{"description1", "description3"}, descriptions(d), "Expected the dedup policy of the receiver to be used")
+		assert.Equal(t, 3, d.Snapshot()[0].Occurrences, "Expected the repeats to be counted")
+	})
+
+	t.Run("With Children", func(t *testing.T) {
+		d := New()
+		other := New()
+		other.AddInfo("info1")
+		other.Child("parse").AddWarning("warning1")
+
+		d.Merge(other)
+
+		items := d.Snapshot()
+		assert.Equal(t, 2, len(items), "Expected the items of the children to be merged")
+		assert.Equal(t, "parse", items[1].Scope, "Expected the scope of the item to be kept")
+		assert.Equal(t, 2, other.Len(), "Expected the merged diagnostics to be unchanged")
+	})
+
+	t.Run("Resequences the items", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+		other := New()
+		other.AddInfo("info2")
+		other.AddInfo("info3")
+		d.AddInfo("info4")
+
+		d.Merge(other)
+
+		assert.Equal(t, []string{"info1", "info4", "info2", "info3"}, descriptions(d), "Expected merged items to be added last")
+	})
+
+	t.Run("Re-parents the trace id", func(t *testing.T) {
+		d := New()
+		other := New()
+		other.AddInfo("info1")
+
+		d.Merge(other)
+
+		assert.Equal(t, "", d.Snapshot()[0].TraceID, "Expected the item to use the trace id of the receiver")
+		assert.Contains(t, d.String(), "["+d.GetTraceID()+"]", "Expected the receiver trace id to be rendered")
+	})
+
+	t.Run("Preserve Trace ID", func(t *testing.T) {
+		d := New()
+		other := New()
+		other.AddInfo("info1")
+
+		d.Merge(other, PreserveTraceID())
+
+		assert.Equal(t, other.GetTraceID(), d.Snapshot()[0].TraceID, "Expected the item to keep its trace id")
+		assert.Contains(t, d.String(), "["+other.GetTraceID()+"]", "Expected the item trace id to be rendered")
+	})
+
+	t.Run("Record Origin", func(t *testing.T) {
+		d := New()
+		other := New(WithW3CTraceContext())
+		other.AddInfo("info1")
+		d.AddInfo("info2")
+
+		d.Merge(other, RecordOrigin())
+
+		items := d.Snapshot()
+		assert.Equal(t, "", items[0].Origin, "Expected the items of the receiver to have no origin")
+		assert.Equal(t, other.TraceParent(), items[1].Origin, "Expected the origin to be the merged traceparent")
+	})
+
+	t.Run("Min Level", func(t *testing.T) {
+		d := New(WithMinLevel(Warning))
+		other := New()
+		other.AddInfo("info1")
+		other.AddWarning("warning1")
+
+		d.Merge(other)
+
+		assert.Equal(t, []string{"warning1"}, descriptions(d), "Expected the items below the minimum level to be dropped")
+	})
+
+	t.Run("Prefer Highest Severity counts the replaced items", func(t *testing.T) {
+		d := New(WithDedupPolicy(DedupNone))
+		d.AddItem(NewWarning("code1", "description1"))
+		d.AddItem(NewWarning("code1", "description2"))
+		other := New()
+		other.AddItem(NewError("code1", "description3"))
+
+		d.Merge(other, WithMergeStrategy(MergePreferHighestSeverity))
+
+		items := d.Snapshot()
+		assert.Equal(t, []string{"description3"}, descriptions(d), "Expected a single item per code")
+		assert.Equal(t, 3, items[0].Occurrences, "Expected the occurrences of the replaced items to be counted")
+	})
+
+	t.Run("Dedup By Code keeps the scopes apart", func(t *testing.T) {
+		d := New()
+		d.AddItem(NewWarning("code1", "description1"))
+		other := New()
+		other.Child("parse").AddItem(NewWarning("code1", "description2"))
+
+		d.Merge(other, WithMergeStrategy(MergeDedupByCode))
+
+		assert.Equal(t, []string{"description1", "description2"}, descriptions(d), "Expected items in other scopes to be kept")
+	})
+
+	t.Run("Descendant", func(t *testing.T) {
+		d := New()
+		d.Child("parse").AddInfo("info1")
+
+		d.Merge(d.Child("parse"))
+		d.Merge(d)
+
+		assert.Equal(t, 1, d.Len(), "Expected merging a descendant to be a no-op")
+	})
+
+	t.Run("Ancestor", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+		child := d.Child("parse")
+		child.AddInfo("info2")
+
+		child.Merge(d)
+
+		assert.Equal(t, 1, child.Len(), "Expected merging an ancestor to be a no-op")
+		assert.Equal(t, 1, child.Snapshot()[0].Occurrences, "Expected the items of the child not to be repeated")
+		assert.Equal(t, 2, d.Len(), "Expected the ancestor items not to be reported twice")
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		d := New()
+
+		assert.NotPanics(t, func() { d.Merge(nil) }, "Expected merging nil to be a no-op")
+	})
+}
@@ -0,0 +1,69 @@
+package diagnostics
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// ContextWithMetadata adds metadata to ctx, any diagnostics created from the
+// context inherits it. args are either key value pairs or slog.Attr values.
+func ContextWithMetadata(ctx context.Context, args ...any) context.Context {
+	metadata := mergeMetadata(MetadataFromContext(ctx), slog.Group("", args...).Value.Group())
+	return context.WithValue(ctx, metadataContextKey, metadata)
+}
+
+func MetadataFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+
+	metadata, _ := ctx.Value(metadataContextKey).([]slog.Attr)
+	return metadata
+}
+
+// SetMetadata adds metadata to the diagnostics and to its context, an existing
+// key is replaced.
+func (d *Diagnostics) SetMetadata(args ...any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.metadata = mergeMetadata(d.metadata, slog.Group("", args...).Value.Group())
+	d.ctx = context.WithValue(d.ctx, metadataContextKey, d.metadata)
+}
+
+func (d *Diagnostics) Metadata() []slog.Attr {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return append([]slog.Attr{}, d.metadata...)
+}
+
+func mergeMetadata(metadata []slog.Attr, attrs []slog.Attr) []slog.Attr {
+	result := make([]slog.Attr, 0, len(metadata)+len(attrs))
+	result = append(result, metadata...)
+	for _, attr := range attrs {
+		replaced := false
+		for index, existing := range result {
+			if existing.Key == attr.Key {
+				result[index] = attr
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			result = append(result, attr)
+		}
+	}
+
+	return result
+}
+
+func formatMetadata(metadata []slog.Attr) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(formatAttributes("", metadata), " ") + "}"
+}
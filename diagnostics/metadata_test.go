@@ -0,0 +1,105 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextWithMetadata(t *testing.T) {
+	ctx := ContextWithMetadata(context.Background(), TenantMetadata, "acme", OperationMetadata, "import")
+	ctx = ContextWithMetadata(ctx, OperationMetadata, "export", slog.String(UserMetadata, "john"))
+
+	expected := []slog.Attr{
+		slog.String(TenantMetadata, "acme"),
+		slog.String(OperationMetadata, "export"),
+		slog.String(UserMetadata, "john"),
+	}
+	assert.Equal(t, expected, MetadataFromContext(ctx), "Expected the metadata to be merged")
+	assert.Nil(t, MetadataFromContext(context.Background()), "Expected no metadata")
+}
+
+func TestDiagnostics_Metadata(t *testing.T) {
+	t.Run("With option", func(t *testing.T) {
+		d := New(WithMetadata(TenantMetadata, "acme", VersionMetadata, "1.0.0"))
+
+		expected := []slog.Attr{slog.String(TenantMetadata, "acme"), slog.String(VersionMetadata, "1.0.0")}
+		assert.Equal(t, expected, d.Metadata(), "Expected the option metadata")
+		assert.Equal(t, expected, MetadataFromContext(d.Context()), "Expected the context to carry the metadata")
+	})
+
+	t.Run("Propagated through FromContext", func(t *testing.T) {
+		ctx := ContextWithMetadata(context.Background(), TenantMetadata, "acme")
+		d := FromContext(ctx, WithMetadata(HostMetadata, "worker-1"))
+
+		expected := []slog.Attr{slog.String(TenantMetadata, "acme"), slog.String(HostMetadata, "worker-1")}
+		assert.Equal(t, expected, d.Metadata(), "Expected the context metadata to be inherited")
+
+		inherited := NewFromContext(d.Context())
+		assert.Equal(t, expected, inherited.Metadata(), "Expected diagnostics built from the context to inherit the metadata")
+	})
+
+	t.Run("SetMetadata", func(t *testing.T) {
+		d := New(WithMetadata(TenantMetadata, "acme"))
+		d.SetMetadata(UserMetadata, "john", TenantMetadata, "globex")
+
+		expected := []slog.Attr{slog.String(TenantMetadata, "globex"), slog.String(UserMetadata, "john")}
+		assert.Equal(t, expected, d.Metadata(), "Expected the metadata to be replaced and added")
+		assert.Equal(t, expected, MetadataFromContext(d.Context()), "Expected the context to be updated")
+		assert.Equal(t, expected, d.Child("import").Metadata(), "Expected the child to inherit the metadata")
+	})
+
+	t.Run("String", func(t *testing.T) {
+		d := New(WithMetadata(TenantMetadata, "acme", OperationMetadata, "import users"))
+		d.traceID = "test-trace-id"
+		d.AddInfo("info1")
+		d.Child("parse").AddWarning("warning1")
+
+		expected := "[test-trace-id]{tenant=acme operation=\"import users\"}[Info] info1\n" +
+			"[test-trace-id]{tenant=acme operation=\"import users\"}[parse][Warning] warning1\n"
+		assert.Equal(t, expected, d.String(), "Expected the metadata in every line")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		d := New(WithMetadata(TenantMetadata, "acme"))
+		d.AddInfo("info1")
+		child := d.Child("import")
+		child.SetMetadata(OperationMetadata, "import")
+		d.Child("export")
+
+		b, err := json.Marshal(d)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		assert.Contains(t, string(b), `"metadata":{"tenant":"acme"}`, "Expected the root metadata")
+		assert.Contains(t, string(b), `"metadata":{"operation":"import","tenant":"acme"}`, "Expected the child metadata when it differs")
+		assert.Contains(t, string(b), `{"name":"export","items":[]}`, "Expected no metadata for children inheriting it")
+
+		var result Diagnostics
+		assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+		assert.Equal(t, d.Metadata(), result.Metadata(), "Expected the metadata to be kept")
+		assert.Equal(t, d.Metadata(), MetadataFromContext(result.Context()), "Expected the context to carry the metadata")
+		assert.Equal(t, d.Metadata(), result.Children()[1].Metadata(), "Expected the children to inherit the metadata")
+		assert.Equal(t, 2, len(result.Children()[0].Metadata()), "Expected the child metadata to be kept")
+	})
+
+	t.Run("Exports", func(t *testing.T) {
+		d := New(WithMetadata(TenantMetadata, "acme"))
+		d.AddError(assert.AnError)
+
+		problem := d.ProblemDetails()
+		assert.Equal(t, map[string]any{TenantMetadata: "acme"}, problem.Metadata, "Expected the metadata in the problem details")
+
+		var buffer bytes.Buffer
+		d.Log(slog.New(slog.NewTextHandler(&buffer, nil)))
+		assert.Contains(t, buffer.String(), "tenant=acme", "Expected the metadata in the log records")
+
+		b, err := json.Marshal(problem)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		parsed, err := ParseProblemDetails(b)
+		assert.NoError(t, err, "Unexpected error while parsing")
+		assert.Equal(t, d.Metadata(), parsed.Metadata(), "Expected the metadata to be parsed")
+	})
+}
@@ -0,0 +1,65 @@
+package diagnostics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Exporter is called by the middleware once the request is handled.
+type Exporter func(r *http.Request, d *Diagnostics)
+
+func Middleware(next http.Handler) http.Handler {
+	return MiddlewareWithExporter(LogExporter)(next)
+}
+
+// MiddlewareWithExporter reuses the trace of the traceparent or X-Trace-Id
+// request headers and echoes it in the response headers. A diagnostics already
+// attached to the request context by an outer layer is reused, it is exported
+// by that layer and not by the middleware.
+func MiddlewareWithExporter(exporter Exporter, options ...Option) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, attached := diagnosticsFromContext(r.Context())
+			if !attached {
+				ctx := r.Context()
+				if traceContext, err := ParseTraceContext(r.Header.Get(TraceParentHeader), r.Header.Get(TraceStateHeader)); err == nil {
+					ctx = ContextWithTraceContext(ctx, traceContext)
+				} else if traceID := strings.TrimSpace(r.Header.Get(TraceIDHeader)); traceID != "" {
+					ctx = context.WithValue(ctx, TraceID, traceID)
+				}
+
+				d = NewFromContext(ctx, options...)
+				r = r.WithContext(d.Context())
+			}
+			w.Header().Set(TraceIDHeader, d.GetTraceID())
+			if traceParent := d.TraceParent(); traceParent != "" {
+				w.Header().Set(TraceParentHeader, traceParent)
+			}
+			if traceState := d.TraceState(); traceState != "" {
+				w.Header().Set(TraceStateHeader, traceState)
+			}
+
+			if exporter != nil && !attached {
+				defer exporter(r, d)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// FromRequest returns the diagnostics attached by the middleware, see
+// FromContext.
+func FromRequest(r *http.Request) *Diagnostics {
+	return FromContext(r.Context())
+}
+
+func LogExporter(r *http.Request, d *Diagnostics) {
+	if d.Len() == 0 {
+		return
+	}
+
+	log.Printf("%v %v\n%v", r.Method, r.URL.Path, d.String())
+}
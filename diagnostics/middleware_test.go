@@ -0,0 +1,157 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("Without Trace Headers", func(t *testing.T) {
+		var handled *Diagnostics
+		handler := MiddlewareWithExporter(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = FromRequest(r)
+			handled.AddInfo("handled")
+		}))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.NotNil(t, handled, "Expected the handler to get the diagnostics")
+		assert.NotEmpty(t, handled.GetTraceID(), "Expected a trace id to be generated")
+		assert.Equal(t, handled.GetTraceID(), recorder.Header().Get(TraceIDHeader), "Expected the trace id to be echoed")
+	})
+
+	t.Run("With X-Trace-Id Header", func(t *testing.T) {
+		var handled *Diagnostics
+		handler := MiddlewareWithExporter(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = FromRequest(r)
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(TraceIDHeader, "test-trace-id")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, "test-trace-id", handled.GetTraceID(), "Expected the trace id to be reused")
+		assert.Equal(t, "test-trace-id", handled.Context().Value(TraceID), "Expected the context to carry the trace id")
+		assert.Equal(t, "test-trace-id", recorder.Header().Get(TraceIDHeader), "Expected the trace id to be echoed")
+	})
+
+	t.Run("With traceparent Header", func(t *testing.T) {
+		var handled *Diagnostics
+		handler := MiddlewareWithExporter(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = FromRequest(r)
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		request.Header.Set(TraceStateHeader, "vendor=value")
+		request.Header.Set(TraceIDHeader, "test-trace-id")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", handled.GetTraceID(), "Expected the traceparent trace id to be used")
+		assert.NotEqual(t, "00f067aa0ba902b7", handled.SpanID(), "Expected a new span for the request")
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", recorder.Header().Get(TraceIDHeader), "Expected the trace id to be echoed")
+		assert.Equal(t, handled.TraceParent(), recorder.Header().Get(TraceParentHeader), "Expected the traceparent to be echoed")
+		assert.Equal(t, "vendor=value", recorder.Header().Get(TraceStateHeader), "Expected the tracestate to be echoed")
+	})
+
+	t.Run("With W3C option and no headers", func(t *testing.T) {
+		var handled *Diagnostics
+		handler := MiddlewareWithExporter(nil, WithW3CTraceContext())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = FromRequest(r)
+		}))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.NotEmpty(t, handled.TraceParent(), "Expected a W3C trace context")
+		assert.Equal(t, handled.TraceParent(), recorder.Header().Get(TraceParentHeader), "Expected the traceparent to be echoed")
+	})
+
+	t.Run("With invalid traceparent Header", func(t *testing.T) {
+		var handled *Diagnostics
+		handler := MiddlewareWithExporter(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled = FromRequest(r)
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(TraceParentHeader, "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+		request.Header.Set(TraceIDHeader, "test-trace-id")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.Equal(t, "test-trace-id", handled.GetTraceID(), "Expected the X-Trace-Id header to be used")
+	})
+
+	t.Run("Exports on completion", func(t *testing.T) {
+		var exported *Diagnostics
+		exporter := func(r *http.Request, d *Diagnostics) {
+			exported = d
+		}
+		handler := MiddlewareWithExporter(exporter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			FromRequest(r).AddError(errors.New("error1"))
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.NotNil(t, exported, "Expected the diagnostics to be exported")
+		assert.True(t, exported.HasErrors(), "Expected the exported diagnostics to have the handler errors")
+	})
+
+	t.Run("With attached diagnostics", func(t *testing.T) {
+		upstream := New()
+		var exported *Diagnostics
+		exporter := func(r *http.Request, d *Diagnostics) {
+			exported = d
+		}
+		handler := MiddlewareWithExporter(exporter)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			FromRequest(r).AddInfo("handled")
+		}))
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(TraceIDHeader, "test-trace-id")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request.WithContext(upstream.Context()))
+
+		assert.Equal(t, []string{"handled"}, upstream.Info(), "Expected the handler items to reach the attached diagnostics")
+		assert.Equal(t, upstream.GetTraceID(), recorder.Header().Get(TraceIDHeader), "Expected the trace id of the attached diagnostics to be echoed")
+		assert.Nil(t, exported, "Expected the attached diagnostics to be exported by its owner")
+	})
+}
+
+func TestFromRequest(t *testing.T) {
+	t.Run("Without Middleware", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), TraceID, "test-trace-id")
+		request := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+		d := FromRequest(request)
+
+		assert.Equal(t, "test-trace-id", d.GetTraceID(), "Expected the context trace id to be used")
+	})
+}
+
+func TestLogExporter(t *testing.T) {
+	var buffer bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&buffer)
+	defer log.SetOutput(output)
+
+	d := New()
+	request := httptest.NewRequest(http.MethodGet, "/users", nil)
+
+	LogExporter(request, d)
+	assert.Empty(t, buffer.String(), "Expected nothing to be logged without items")
+
+	d.AddWarning("warning1")
+	LogExporter(request, d)
+	assert.Contains(t, buffer.String(), "GET /users", "Expected the request to be logged")
+	assert.Contains(t, buffer.String(), "[Warning] warning1", "Expected the items to be logged")
+}
@@ -0,0 +1,87 @@
+package diagnostics
+
+import (
+	"log/slog"
+	"time"
+)
+
+type Option func(d *Diagnostics)
+
+type Clock func() time.Time
+
+// WithW3CTraceContext makes the diagnostics use a W3C trace context, the trace
+// id is a 16 bytes hex string and each diagnostics and child gets its own
+// span id.
+func WithW3CTraceContext() Option {
+	return func(d *Diagnostics) {
+		d.config.w3cTraceContext = true
+	}
+}
+
+// WithSourceCapture sets which items get the location of the code adding them.
+func WithSourceCapture(mode SourceCapture) Option {
+	return func(d *Diagnostics) {
+		d.config.sourceCapture = mode
+	}
+}
+
+// WithStackTrace captures the stack trace of the code adding error items.
+func WithStackTrace() Option {
+	return func(d *Diagnostics) {
+		d.config.stackTrace = true
+	}
+}
+
+// WithClock sets the clock used to timestamp the items, it defaults to
+// time.Now.
+func WithClock(clock Clock) Option {
+	return func(d *Diagnostics) {
+		d.config.clock = clock
+	}
+}
+
+// WithMetadata adds metadata to the diagnostics, it is added to the one
+// carried by the context.
+func WithMetadata(args ...any) Option {
+	return func(d *Diagnostics) {
+		d.metadata = mergeMetadata(d.metadata, slog.Group("", args...).Value.Group())
+	}
+}
+
+// WithMinLevel drops the items below level, by default all the items are kept.
+func WithMinLevel(level DiagnosticLevel) Option {
+	return func(d *Diagnostics) {
+		d.config.minLevel = &level
+	}
+}
+
+// WithDedupPolicy sets how repeated items are detected, it defaults to
+// DedupCaseInsensitive.
+func WithDedupPolicy(policy DedupPolicy) Option {
+	return func(d *Diagnostics) {
+		d.config.dedup = policy
+	}
+}
+
+// WithCapacity limits the number of items of level kept by each diagnostics of
+// the tree, once reached the new items are dropped unless WithRingBuffer is
+// used. The limit applies to each child on its own, Close moves the items of a
+// child into its parent and under its limit.
+func WithCapacity(level DiagnosticLevel, limit int) Option {
+	return func(d *Diagnostics) {
+		capacity := make(map[DiagnosticLevel]int, len(d.config.capacity)+1)
+		for l, c := range d.config.capacity {
+			capacity[l] = c
+		}
+		capacity[level] = max(limit, 0)
+		d.config.capacity = capacity
+	}
+}
+
+// WithRingBuffer drops the oldest items of a level instead of the new ones when
+// its capacity is reached.
+func WithRingBuffer() Option {
+	return func(d *Diagnostics) {
+		d.config.ringBuffer = true
+	}
+}
@@ -0,0 +1,215 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrNoProblem is returned by Write when the diagnostics have no error.
+var ErrNoProblem = errors.New("no error to write as a problem")
+
+const (
+	ProblemDetailsContentType = "application/problem+json"
+	DefaultProblemType        = "about:blank"
+)
+
+type ProblemDetails struct {
+	Type     string               `json:"type,omitempty"`
+	Title    string               `json:"title,omitempty"`
+	Status   int                  `json:"status,omitempty"`
+	Detail   string               `json:"detail,omitempty"`
+	Instance string               `json:"instance,omitempty"`
+	Metadata map[string]any       `json:"metadata,omitempty"`
+	Errors   []ProblemDetailsItem `json:"errors,omitempty"`
+}
+
+type ProblemDetailsItem struct {
+	Type   string          `json:"type,omitempty"`
+	Code   string          `json:"code,omitempty"`
+	Detail string          `json:"detail"`
+	Level  DiagnosticLevel `json:"level"`
+	Scope  string          `json:"scope,omitempty"`
+}
+
+// ProblemType describes how items with a given code are rendered, an empty
+// Title defaults to the status text.
+type ProblemType struct {
+	Type   string
+	Title  string
+	Status int
+}
+
+// ProblemRenderer converts diagnostics to and from RFC 9457 problem details,
+// types should be registered before the renderer is used.
+type ProblemRenderer struct {
+	DefaultType ProblemType
+	types       map[string]ProblemType
+	// codes maps the types back to the first code registered for them.
+	codes map[string]string
+	order []string
+}
+
+func NewProblemRenderer() *ProblemRenderer {
+	return &ProblemRenderer{
+		DefaultType: ProblemType{
+			Type:   DefaultProblemType,
+			Status: http.StatusInternalServerError,
+		},
+		types: map[string]ProblemType{},
+		codes: map[string]string{},
+	}
+}
+
+// Register sets the problem type of code, when several codes share a type the
+// first one registered is used to parse it back.
+func (r *ProblemRenderer) Register(code string, problemType ProblemType) *ProblemRenderer {
+	if _, ok := r.types[code]; !ok {
+		r.order = append(r.order, code)
+	}
+	r.types[code] = problemType
+
+	r.codes = make(map[string]string, len(r.order))
+	for _, c := range r.order {
+		if _, ok := r.codes[r.types[c].Type]; !ok {
+			r.codes[r.types[c].Type] = c
+		}
+	}
+	return r
+}
+
+func (r *ProblemRenderer) problemType(code string) ProblemType {
+	result, ok := r.types[code]
+	if !ok {
+		result = r.DefaultType
+	}
+	if result.Type == "" {
+		result.Type = DefaultProblemType
+	}
+	if result.Status == 0 {
+		result.Status = r.DefaultType.Status
+	}
+	if result.Title == "" {
+		result.Title = http.StatusText(result.Status)
+	}
+
+	return result
+}
+
+func (r *ProblemRenderer) code(problemType string) string {
+	return r.codes[problemType]
+}
+
+// Render builds the problem details of d, the type, title, status and detail
+// are taken from the error with the highest status and the errors and
+// warnings are listed in the errors extension. Without errors the status is
+// 200, the warnings are still listed.
+func (r *ProblemRenderer) Render(d *Diagnostics) *ProblemDetails {
+	result := &ProblemDetails{
+		Type:     DefaultProblemType,
+		Title:    http.StatusText(http.StatusOK),
+		Status:   http.StatusOK,
+		Instance: d.GetTraceID(),
+		Metadata: attributesToJSON(d.Metadata()),
+	}
+
+	var primary *ProblemType
+	for _, i := range d.Snapshot() {
+		if !i.Level.AtLeast(Warning) {
+			continue
+		}
+
+		problemType := r.problemType(i.Code)
+		item := ProblemDetailsItem{
+			Code:   i.Code,
+			Detail: i.Description,
+			Level:  i.Level,
+			Scope:  i.Scope,
+		}
+		if problemType.Type != DefaultProblemType {
+			item.Type = problemType.Type
+		}
+		result.Errors = append(result.Errors, item)
+
+		if i.Level.AtLeast(Error) && (primary == nil || problemType.Status > primary.Status) {
+			primary = &problemType
+			result.Detail = i.Description
+		}
+	}
+
+	if primary != nil {
+		result.Type = primary.Type
+		result.Title = primary.Title
+		result.Status = primary.Status
+	}
+
+	return result
+}
+
+// Write writes the problem details of d as the response, a problem describes
+// an error so nothing is written and ErrNoProblem is returned when d has no
+// error.
+func (r *ProblemRenderer) Write(w http.ResponseWriter, d *Diagnostics) error {
+	if !d.HasErrors() {
+		return ErrNoProblem
+	}
+
+	problem := r.Render(d)
+
+	w.Header().Set("Content-Type", ProblemDetailsContentType)
+	w.WriteHeader(problem.Status)
+	return json.NewEncoder(w).Encode(problem)
+}
+
+// Parse rebuilds the diagnostics from a problem details document, the
+// instance is used as the trace id.
+func (r *ProblemRenderer) Parse(data []byte) (*Diagnostics, error) {
+	var problem ProblemDetails
+	if err := json.Unmarshal(data, &problem); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if problem.Instance != "" {
+		ctx = context.WithValue(ctx, TraceID, problem.Instance)
+	}
+
+	if len(problem.Metadata) > 0 {
+		ctx = context.WithValue(ctx, metadataContextKey, attributesFromJSON(problem.Metadata))
+	}
+
+	result := NewFromContext(ctx)
+	for _, i := range problem.Errors {
+		code := i.Code
+		if code == "" && i.Type != "" {
+			code = r.code(i.Type)
+		}
+
+		result.AddItem(&DiagnosticItem{
+			Code:        code,
+			Description: i.Detail,
+			Level:       i.Level,
+			Scope:       i.Scope,
+		})
+	}
+
+	if len(problem.Errors) == 0 && problem.Status >= http.StatusBadRequest {
+		description := problem.Detail
+		if description == "" {
+			description = problem.Title
+		}
+
+		result.AddItem(newDiagnosticItem(r.code(problem.Type), description, Error))
+	}
+
+	return result, nil
+}
+
+func (d *Diagnostics) ProblemDetails() *ProblemDetails {
+	return NewProblemRenderer().Render(d)
+}
+
+func ParseProblemDetails(data []byte) (*Diagnostics, error) {
+	return NewProblemRenderer().Parse(data)
+}
@@ -0,0 +1,146 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemRenderer_Render(t *testing.T) {
+	t.Run("Without Errors", func(t *testing.T) {
+		d := New()
+		d.AddInfo("info1")
+
+		problem := NewProblemRenderer().Render(d)
+
+		assert.Equal(t, DefaultProblemType, problem.Type, "Expected the type to be the default type")
+		assert.Equal(t, http.StatusOK, problem.Status, "Expected the status to be 200")
+		assert.Equal(t, d.GetTraceID(), problem.Instance, "Expected the instance to be the trace id")
+		assert.Empty(t, problem.Errors, "Expected no errors")
+	})
+
+	t.Run("With unmapped Errors", func(t *testing.T) {
+		d := New()
+		d.AddWarning("warning1")
+		d.AddErrorWithCode("code1", errors.New("description1"))
+		d.AddTrace("trace1")
+
+		problem := d.ProblemDetails()
+
+		assert.Equal(t, DefaultProblemType, problem.Type, "Expected the type to be the default type")
+		assert.Equal(t, http.StatusInternalServerError, problem.Status, "Expected the status to be 500")
+		assert.Equal(t, "Internal Server Error", problem.Title, "Expected the title to be the status text")
+		assert.Equal(t, "description1", problem.Detail, "Expected the detail to be the error description")
+		assert.Equal(t, []ProblemDetailsItem{
+			{Detail: "warning1", Level: Warning},
+			{Code: "code1", Detail: "description1", Level: Error},
+		}, problem.Errors, "Expected the errors extension to hold the warnings and errors")
+	})
+
+	t.Run("With mapped Errors", func(t *testing.T) {
+		renderer := NewProblemRenderer().
+			Register("NOT_FOUND", ProblemType{Type: "https://example.com/problems/not-found", Status: http.StatusNotFound}).
+			Register("CONFLICT", ProblemType{Type: "https://example.com/problems/conflict", Title: "Conflict detected", Status: http.StatusConflict})
+
+		d := New()
+		d.AddErrorWithCode("NOT_FOUND", errors.New("user was not found"))
+		d.Child("update").AddErrorWithCode("CONFLICT", errors.New("user was updated"))
+
+		problem := renderer.Render(d)
+
+		assert.Equal(t, "https://example.com/problems/conflict", problem.Type, "Expected the type of the highest status")
+		assert.Equal(t, "Conflict detected", problem.Title, "Expected the registered title")
+		assert.Equal(t, http.StatusConflict, problem.Status, "Expected the highest status")
+		assert.Equal(t, "user was updated", problem.Detail, "Expected the detail of the highest status")
+		assert.Equal(t, []ProblemDetailsItem{
+			{Type: "https://example.com/problems/not-found", Code: "NOT_FOUND", Detail: "user was not found", Level: Error},
+			{Type: "https://example.com/problems/conflict", Code: "CONFLICT", Detail: "user was updated", Level: Error, Scope: "update"},
+		}, problem.Errors, "Expected the errors extension to hold the mapped types")
+	})
+}
+
+func TestProblemRenderer_Write(t *testing.T) {
+	renderer := NewProblemRenderer().
+		Register("NOT_FOUND", ProblemType{Type: "https://example.com/problems/not-found", Status: http.StatusNotFound})
+	d := New()
+	d.AddErrorWithCode("NOT_FOUND", errors.New("user was not found"))
+
+	recorder := httptest.NewRecorder()
+	err := renderer.Write(recorder, d)
+
+	assert.NoError(t, err, "Unexpected error while writing")
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "Expected the status to be 404")
+	assert.Equal(t, ProblemDetailsContentType, recorder.Header().Get("Content-Type"), "Expected the problem details content type")
+
+	var problem ProblemDetails
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem), "Unexpected error while unmarshaling")
+	assert.Equal(t, d.GetTraceID(), problem.Instance, "Expected the instance to be the trace id")
+
+	t.Run("Without errors", func(t *testing.T) {
+		d := New()
+		d.AddWarning("warning1")
+
+		recorder := httptest.NewRecorder()
+		err := renderer.Write(recorder, d)
+
+		assert.ErrorIs(t, err, ErrNoProblem, "Expected no problem to be written")
+		assert.Empty(t, recorder.Body.String(), "Expected no body")
+		assert.Empty(t, recorder.Header().Get("Content-Type"), "Expected no problem details content type")
+	})
+}
+
+func TestProblemRenderer_Parse(t *testing.T) {
+	renderer := NewProblemRenderer().
+		Register("NOT_FOUND", ProblemType{Type: "https://example.com/problems/not-found", Status: http.StatusNotFound})
+
+	t.Run("With Errors extension", func(t *testing.T) {
+		d := New()
+		d.AddWarning("warning1")
+		d.Child("lookup").AddErrorWithCode("NOT_FOUND", errors.New("user was not found"))
+
+		b, err := json.Marshal(renderer.Render(d))
+		assert.NoError(t, err, "Unexpected error while marshaling")
+
+		result, err := renderer.Parse(b)
+		assert.NoError(t, err, "Unexpected error while parsing")
+		assert.Equal(t, d.GetTraceID(), result.GetTraceID(), "Expected the trace id to be the instance")
+		assert.Equal(t, d.String(), result.String(), "Expected the same items")
+	})
+
+	t.Run("Without Errors extension", func(t *testing.T) {
+		data := `{"type":"https://example.com/problems/not-found","title":"Not Found","status":404,"detail":"user was not found","instance":"test-trace-id"}`
+
+		result, err := renderer.Parse([]byte(data))
+		assert.NoError(t, err, "Unexpected error while parsing")
+		assert.Equal(t, "test-trace-id", result.GetTraceID(), "Expected the trace id to be the instance")
+		assert.Equal(t, "[test-trace-id][Error] NOT_FOUND: user was not found\n", result.String(), "Expected an error with the mapped code")
+	})
+
+	t.Run("Shared type", func(t *testing.T) {
+		renderer := NewProblemRenderer().
+			Register("USER_NOT_FOUND", ProblemType{Type: "https://example.com/problems/not-found", Status: http.StatusNotFound}).
+			Register("ORDER_NOT_FOUND", ProblemType{Type: "https://example.com/problems/not-found", Status: http.StatusNotFound}).
+			Register("ITEM_NOT_FOUND", ProblemType{Type: "https://example.com/problems/not-found", Status: http.StatusNotFound})
+		data := `{"type":"https://example.com/problems/not-found","status":404,"detail":"user was not found"}`
+
+		for i := 0; i < 20; i++ {
+			result, err := renderer.Parse([]byte(data))
+			assert.NoError(t, err, "Unexpected error while parsing")
+			assert.Equal(t, "USER_NOT_FOUND", result.Snapshot()[0].Code, "Expected the first registered code")
+		}
+
+		renderer.Register("USER_NOT_FOUND", ProblemType{Type: "https://example.com/problems/user-not-found", Status: http.StatusNotFound})
+		result, err := renderer.Parse([]byte(data))
+		assert.NoError(t, err, "Unexpected error while parsing")
+		assert.Equal(t, "ORDER_NOT_FOUND", result.Snapshot()[0].Code, "Expected the next registered code once the first one changed its type")
+	})
+
+	t.Run("Invalid document", func(t *testing.T) {
+		_, err := ParseProblemDetails([]byte(`{"status":"invalid"}`))
+		assert.Error(t, err, "Expected an error for an invalid document")
+	})
+}
@@ -0,0 +1,151 @@
+package diagnostics
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Query filters the items of a diagnostics and of its children, each filter
+// returns a new query so a query can be reused as the base of other ones.
+type Query struct {
+	d       *Diagnostics
+	filters []func(item *DiagnosticItem) bool
+}
+
+func (d *Diagnostics) Query() *Query {
+	return &Query{d: d}
+}
+
+// Where keeps the items matching fn, fn is called with a copy of the items
+// without holding the lock so it can use the diagnostics.
+func (q *Query) Where(fn func(item *DiagnosticItem) bool) *Query {
+	filters := make([]func(item *DiagnosticItem) bool, len(q.filters), len(q.filters)+1)
+	copy(filters, q.filters)
+	return &Query{d: q.d, filters: append(filters, fn)}
+}
+
+func (q *Query) Level(level DiagnosticLevel) *Query {
+	return q.Where(func(item *DiagnosticItem) bool {
+		return item.Level == level
+	})
+}
+
+func (q *Query) AtLeast(level DiagnosticLevel) *Query {
+	return q.Where(func(item *DiagnosticItem) bool {
+		return item.Level.AtLeast(level)
+	})
+}
+
+// LevelRange keeps the items between from and to, both included.
+func (q *Query) LevelRange(from DiagnosticLevel, to DiagnosticLevel) *Query {
+	return q.Where(func(item *DiagnosticItem) bool {
+		return item.Level >= from && item.Level <= to
+	})
+}
+
+func (q *Query) CodePrefix(prefix string) *Query {
+	return q.Where(func(item *DiagnosticItem) bool {
+		return strings.HasPrefix(item.Code, prefix)
+	})
+}
+
+func (q *Query) Description(pattern *regexp.Regexp) *Query {
+	return q.Where(func(item *DiagnosticItem) bool {
+		return pattern.MatchString(item.Description)
+	})
+}
+
+// Scope keeps the items of the scope and of its sub scopes.
+func (q *Query) Scope(scope string) *Query {
+	return q.Where(func(item *DiagnosticItem) bool {
+		return item.Scope == scope || strings.HasPrefix(item.Scope, scope+"/")
+	})
+}
+
+func (q *Query) match(item *DiagnosticItem) bool {
+	for _, filter := range q.filters {
+		if !filter(item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Items returns a copy of the matching items in the order they were added.
+func (q *Query) Items() []*DiagnosticItem {
+	result := []*DiagnosticItem{}
+	for _, i := range q.d.Snapshot() {
+		if q.match(i) {
+			result = append(result, i)
+		}
+	}
+
+	return result
+}
+
+// Diagnostics returns a new diagnostics with the same trace and metadata
+// holding a copy of the matching items, the items keep their scope.
+func (q *Query) Diagnostics() *Diagnostics {
+	items := q.Items()
+
+	q.d.mu.RLock()
+	result := &Diagnostics{
+		config:       q.d.config,
+		traceID:      q.d.traceID,
+		traceContext: q.d.traceContext,
+		name:         q.d.name,
+		ctx:          q.d.ctx,
+		metadata:     q.d.metadata,
+		createdAt:    q.d.createdAt,
+		sequence:     &atomic.Uint64{},
+		stack:        items,
+	}
+	q.d.mu.RUnlock()
+	result.ctx = NewContext(result.ctx, result)
+	result.config.onItem = nil
+
+	if len(items) > 0 {
+		result.sequence.Store(items[len(items)-1].Sequence)
+	}
+	return result
+}
+
+func (q *Query) Count() int {
+	return len(q.Items())
+}
+
+func (q *Query) Any() bool {
+	_, ok := q.First()
+	return ok
+}
+
+// First returns a copy of the first matching item added.
+func (q *Query) First() (*DiagnosticItem, bool) {
+	for _, i := range q.d.Snapshot() {
+		if q.match(i) {
+			return i, true
+		}
+	}
+
+	return nil, false
+}
+
+func (q *Query) GroupByCode() map[string][]*DiagnosticItem {
+	result := map[string][]*DiagnosticItem{}
+	for _, i := range q.Items() {
+		result[i.Code] = append(result[i.Code], i)
+	}
+
+	return result
+}
+
+func (q *Query) GroupByLevel() map[DiagnosticLevel][]*DiagnosticItem {
+	result := map[DiagnosticLevel][]*DiagnosticItem{}
+	for _, i := range q.Items() {
+		result[i.Level] = append(result[i.Level], i)
+	}
+
+	return result
+}
@@ -0,0 +1,175 @@
+package diagnostics
+
+import (
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newQueryDiagnostics() *Diagnostics {
+	d := New(WithMetadata(TenantMetadata, "acme"))
+	d.AddItem(NewTrace("", "connecting to the database"))
+	d.AddItem(NewInfo("IMP001", "import started"))
+	child := d.Child("import")
+	child.AddItem(NewWarning("IMP100", "row 12 has no id"))
+	child.AddItem(NewError("IMP200", "row 42 is invalid"))
+	d.AddItem(NewCritical("DB500", "database connection lost"))
+	child.Child("parse").AddItem(NewWarning("IMP100", "row 13 has no id"))
+	return d
+}
+
+func descriptionsOf(items []*DiagnosticItem) []string {
+	result := []string{}
+	for _, i := range items {
+		result = append(result, i.Description)
+	}
+	return result
+}
+
+func TestQuery(t *testing.T) {
+	d := newQueryDiagnostics()
+
+	tests := []struct {
+		name     string
+		query    *Query
+		expected []string
+	}{
+		{
+			name:     "All",
+			query:    d.Query(),
+			expected: []string{"connecting to the database", "import started", "row 12 has no id", "row 42 is invalid", "database connection lost", "row 13 has no id"},
+		},
+		{
+			name:     "Level",
+			query:    d.Query().Level(Warning),
+			expected: []string{"row 12 has no id", "row 13 has no id"},
+		},
+		{
+			name:     "At Least",
+			query:    d.Query().AtLeast(Error),
+			expected: []string{"row 42 is invalid", "database connection lost"},
+		},
+		{
+			name:     "Level Range",
+			query:    d.Query().LevelRange(Info, Warning),
+			expected: []string{"import started", "row 12 has no id", "row 13 has no id"},
+		},
+		{
+			name:     "Code Prefix",
+			query:    d.Query().CodePrefix("IMP1"),
+			expected: []string{"row 12 has no id", "row 13 has no id"},
+		},
+		{
+			name:     "Description",
+			query:    d.Query().Description(regexp.MustCompile(`^row \d+ `)),
+			expected: []string{"row 12 has no id", "row 42 is invalid", "row 13 has no id"},
+		},
+		{
+			name:     "Scope",
+			query:    d.Query().Scope("import/parse"),
+			expected: []string{"row 13 has no id"},
+		},
+		{
+			name:     "Composed",
+			query:    d.Query().CodePrefix("IMP").AtLeast(Warning).Description(regexp.MustCompile(`invalid`)),
+			expected: []string{"row 42 is invalid"},
+		},
+		{
+			name:     "No Match",
+			query:    d.Query().Level(Fatal),
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, descriptionsOf(tt.query.Items()), "Unexpected items")
+			assert.Equal(t, len(tt.expected), tt.query.Count(), "Unexpected count")
+			assert.Equal(t, len(tt.expected) > 0, tt.query.Any(), "Unexpected any")
+
+			first, ok := tt.query.First()
+			assert.Equal(t, len(tt.expected) > 0, ok, "Unexpected first")
+			if ok {
+				assert.Equal(t, tt.expected[0], first.Description, "Expected the first item added")
+			}
+		})
+	}
+
+	t.Run("Reused base query", func(t *testing.T) {
+		base := d.Query().CodePrefix("IMP")
+		warnings := base.Level(Warning)
+		errors := base.Level(Error)
+
+		assert.Equal(t, 2, warnings.Count(), "Expected the base query not to be modified")
+		assert.Equal(t, 1, errors.Count(), "Expected the base query not to be modified")
+		assert.Equal(t, 4, base.Count(), "Expected the base query not to be modified")
+	})
+
+	t.Run("Items are copies", func(t *testing.T) {
+		items := d.Query().Level(Info).Items()
+		items[0].Description = "changed"
+
+		assert.Equal(t, []string{"import started"}, descriptionsOf(d.Query().Level(Info).Items()), "Expected the diagnostics to be unchanged")
+	})
+
+	t.Run("Filters using the diagnostics", func(t *testing.T) {
+		d := New()
+		d.AddWarning("warning1")
+		query := d.Query().Where(func(item *DiagnosticItem) bool {
+			d.AddInfo("visited " + item.Description)
+			return item.Level == Warning
+		})
+
+		assert.Equal(t, 1, query.Count(), "Expected the filter to be able to add items")
+		assert.True(t, query.Any(), "Expected the filter to be able to add items")
+		_, ok := query.First()
+		assert.True(t, ok, "Expected the filter to be able to add items")
+	})
+}
+
+func TestQuery_Diagnostics(t *testing.T) {
+	d := newQueryDiagnostics()
+
+	result := d.Query().Level(Warning).Diagnostics()
+
+	assert.Equal(t, d.GetTraceID(), result.GetTraceID(), "Expected the trace id to be kept")
+	assert.Equal(t, d.Metadata(), result.Metadata(), "Expected the metadata to be kept")
+	assert.Equal(t, 2, result.Len(), "Expected only the matching items")
+	assert.False(t, result.HasErrors(), "Expected no errors")
+	assert.Contains(t, result.String(), "[import/parse][Warning] IMP100: row 13 has no id", "Expected the items to keep their scope")
+	assert.Equal(t, 6, d.Len(), "Expected the diagnostics to be unchanged")
+
+	result.AddWarning("warning1")
+	items := result.Snapshot()
+	assert.Equal(t, "warning1", items[len(items)-1].Description, "Expected new items to be added last")
+	assert.Same(t, result, FromContext(result.Context()), "Expected the context to carry the new diagnostics")
+
+	t.Run("Group member", func(t *testing.T) {
+		g := NewGroup(New())
+		var err error
+		g.Go(func(d *Diagnostics) {
+			d.AddWarning("warning1")
+			d.Query().Diagnostics().AddError(io.EOF)
+			err = d.Context().Err()
+		})
+
+		assert.NoError(t, g.Wait(), "Expected the query result not to fail the group")
+		assert.NoError(t, err, "Expected the query result not to cancel the group")
+	})
+}
+
+func TestQuery_GroupBy(t *testing.T) {
+	d := newQueryDiagnostics()
+
+	byCode := d.Query().GroupByCode()
+	assert.Equal(t, 5, len(byCode), "Expected a group per code")
+	assert.Equal(t, []string{"row 12 has no id", "row 13 has no id"}, descriptionsOf(byCode["IMP100"]), "Expected the items of the code in order")
+	assert.Equal(t, []string{"connecting to the database"}, descriptionsOf(byCode[""]), "Expected the items without code to be grouped")
+
+	byLevel := d.Query().AtLeast(Warning).GroupByLevel()
+	assert.Equal(t, 3, len(byLevel), "Expected a group per level")
+	assert.Equal(t, 2, len(byLevel[Warning]), "Expected the warnings to be grouped")
+	assert.Equal(t, []string{"database connection lost"}, descriptionsOf(byLevel[Critical]), "Expected the critical items to be grouped")
+}
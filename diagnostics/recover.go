@@ -0,0 +1,110 @@
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPanic is wrapped by the error of the items recording a panic.
+var ErrPanic = errors.New("panic")
+
+type RecoverOption func(o *recoverOptions)
+
+type recoverOptions struct {
+	code    string
+	level   DiagnosticLevel
+	repanic bool
+}
+
+// WithPanicCode sets the code of the item recording the panic, it defaults to
+// PanicCode.
+func WithPanicCode(code string) RecoverOption {
+	return func(o *recoverOptions) {
+		o.code = code
+	}
+}
+
+// WithPanicLevel sets the level of the item recording the panic, it defaults
+// to Critical.
+func WithPanicLevel(level DiagnosticLevel) RecoverOption {
+	return func(o *recoverOptions) {
+		o.level = level
+	}
+}
+
+// WithRepanic panics again with the recovered value once it is recorded.
+func WithRepanic() RecoverOption {
+	return func(o *recoverOptions) {
+		o.repanic = true
+	}
+}
+
+// Recover records a panic as an item of d, it must be deferred directly:
+//
+//	defer d.Recover()
+func (d *Diagnostics) Recover(options ...RecoverOption) {
+	if value := recover(); value != nil {
+		d.recordPanic(value, options)
+	}
+}
+
+// Guard calls fn and records its panic as an item of d, it returns true when
+// fn panicked.
+func (d *Diagnostics) Guard(fn func(), options ...RecoverOption) (panicked bool) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicked = true
+			d.recordPanic(value, options)
+		}
+	}()
+
+	fn()
+	return false
+}
+
+func (d *Diagnostics) recordPanic(value any, options []RecoverOption) {
+	opts := recoverOptions{code: PanicCode, level: Critical}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	err := fmt.Errorf("%w: %v", ErrPanic, value)
+	if valueErr, ok := value.(error); ok {
+		err = fmt.Errorf("%w: %w", ErrPanic, valueErr)
+	}
+
+	item := &DiagnosticItem{
+		Code:        opts.code,
+		Description: err.Error(),
+		Level:       opts.level,
+		StackTrace:  panicStack(),
+		Err:         err,
+	}
+	if len(item.StackTrace) > 0 {
+		source := item.StackTrace[0]
+		item.Source = &source
+	}
+	d.AddItem(item)
+
+	if opts.repanic {
+		panic(value)
+	}
+}
+
+// panicStack returns the stack of the panicking goroutine starting at the
+// code that panicked, it must be called by the deferred function.
+func panicStack() []SourceLocation {
+	stack := stackFrom(3)
+	for index, frame := range stack {
+		if frame.Function == "runtime.gopanic" {
+			stack = stack[index+1:]
+			break
+		}
+	}
+	for len(stack) > 1 && strings.HasPrefix(stack[0].Function, "runtime.") {
+		stack = stack[1:]
+	}
+
+	return stack
+}
@@ -0,0 +1,115 @@
+package diagnostics
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panicWith(value any) {
+	panic(value)
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("Records the panic", func(t *testing.T) {
+		d := New(WithSourceCapture(SourceCaptureErrors))
+		d.AddInfo("info1")
+
+		func() {
+			defer d.Recover()
+			panicWith("boom")
+		}()
+
+		items := d.Snapshot()
+		assert.Equal(t, 2, len(items), "Expected the items added before the panic to be kept")
+		item := items[1]
+		assert.Equal(t, PanicCode, item.Code, "Expected the panic code")
+		assert.Equal(t, Critical, item.Level, "Expected the panic to be Critical")
+		assert.Equal(t, "panic: boom", item.Description, "Expected the panic value")
+		assert.True(t, errors.Is(item, ErrPanic), "Expected the error to wrap ErrPanic")
+		assert.NotEmpty(t, item.StackTrace, "Expected the stack trace of the panic")
+		assert.True(t, strings.HasSuffix(item.StackTrace[0].Function, ".panicWith"), "Expected the stack trace to start at the panic")
+		assert.Equal(t, item.StackTrace[0], *item.Source, "Expected the source to be the panic")
+		assert.True(t, d.HasErrors(), "Expected the panic to be an error")
+	})
+
+	t.Run("Error value", func(t *testing.T) {
+		d := New()
+
+		func() {
+			defer d.Recover()
+			panicWith(io.EOF)
+		}()
+
+		err := d.Err()
+		assert.True(t, errors.Is(err, io.EOF), "Expected the panic error to be wrapped")
+		assert.True(t, errors.Is(err, ErrPanic), "Expected the error to wrap ErrPanic")
+	})
+
+	t.Run("Runtime error", func(t *testing.T) {
+		d := New()
+
+		func() {
+			defer d.Recover()
+			var values []int
+			_ = values[1]
+		}()
+
+		item := d.Snapshot()[0]
+		assert.Contains(t, item.Description, "index out of range", "Expected the runtime error")
+		assert.Equal(t, "recover_test.go", filepath.Base(item.StackTrace[0].File), "Expected the stack trace to start at the panic")
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		d := New()
+
+		func() {
+			defer d.Recover(WithPanicCode("WORKER"), WithPanicLevel(Error))
+			panicWith("boom")
+		}()
+
+		item := d.Snapshot()[0]
+		assert.Equal(t, "WORKER", item.Code, "Expected the code of the option")
+		assert.Equal(t, Error, item.Level, "Expected the level of the option")
+	})
+
+	t.Run("Repanic", func(t *testing.T) {
+		d := New()
+
+		assert.PanicsWithValue(t, "boom", func() {
+			defer d.Recover(WithRepanic())
+			panicWith("boom")
+		}, "Expected the panic to be raised again")
+		assert.True(t, d.HasErrors(), "Expected the panic to be recorded")
+	})
+
+	t.Run("Without panic", func(t *testing.T) {
+		d := New()
+
+		func() {
+			defer d.Recover()
+		}()
+
+		assert.Equal(t, 0, d.Len(), "Expected no item")
+	})
+}
+
+func TestGuard(t *testing.T) {
+	d := New()
+
+	assert.False(t, d.Guard(func() { d.AddInfo("info1") }), "Expected no panic")
+	assert.True(t, d.Guard(func() { panicWith("boom") }), "Expected the panic to be recovered")
+
+	items := d.Snapshot()
+	assert.Equal(t, 2, len(items), "Expected the panic to be recorded")
+	assert.Equal(t, PanicCode, items[1].Code, "Expected the panic code")
+	assert.True(t, strings.HasSuffix(items[1].StackTrace[0].Function, ".panicWith"), "Expected the stack trace to start at the panic")
+
+	assert.Panics(t, func() {
+		d.Guard(func() { panicWith("boom") }, WithRepanic())
+	}, "Expected the panic to be raised again")
+}
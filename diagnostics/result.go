@@ -0,0 +1,77 @@
+package diagnostics
+
+import "errors"
+
+// Result bundles the value of an operation with the diagnostics it produced,
+// the value is only meaningful when the diagnostics has no errors.
+type Result[T any] struct {
+	Value       T            `json:"value"`
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+func Ok[T any](value T, d *Diagnostics) Result[T] {
+	if d == nil {
+		d = New()
+	}
+
+	return Result[T]{Value: value, Diagnostics: d}
+}
+
+// ErrFailed is added to the diagnostics of a failed result that has no errors.
+var ErrFailed = errors.New("operation failed")
+
+// Fail returns a result without value, d is expected to hold the errors of the
+// operation. ErrFailed is added to d when it has none, so the result is always
+// failed.
+func Fail[T any](d *Diagnostics) Result[T] {
+	if d == nil {
+		d = New()
+	}
+	if !d.HasErrors() {
+		d.AddError(ErrFailed)
+	}
+
+	return Result[T]{Diagnostics: d}
+}
+
+func (r Result[T]) Failed() bool {
+	return r.Diagnostics != nil && r.Diagnostics.HasErrors()
+}
+
+// Unwrap returns the value and the errors of the diagnostics, see
+// Diagnostics.Err.
+func (r Result[T]) Unwrap() (T, error) {
+	if r.Diagnostics == nil {
+		return r.Value, nil
+	}
+
+	return r.Value, r.Diagnostics.Err()
+}
+
+// Map applies fn to the value of r, a failed result is returned as is.
+func Map[T any, U any](r Result[T], fn func(value T) U) Result[U] {
+	if r.Failed() {
+		return Fail[U](r.Diagnostics)
+	}
+
+	return Ok(fn(r.Value), r.Diagnostics)
+}
+
+// Then chains the next step of a pipeline, the diagnostics of the step are
+// merged into the diagnostics of r unless they are already part of it, as
+// with a child of it. fn is not called when r failed.
+func Then[T any, U any](r Result[T], fn func(value T) Result[U]) Result[U] {
+	if r.Failed() {
+		return Fail[U](r.Diagnostics)
+	}
+
+	next := fn(r.Value)
+	if r.Diagnostics == nil {
+		return next
+	}
+	if !r.Diagnostics.contains(next.Diagnostics) {
+		r.Diagnostics.Merge(next.Diagnostics)
+	}
+
+	return Result[U]{Value: next.Value, Diagnostics: r.Diagnostics}
+}
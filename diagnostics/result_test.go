@@ -0,0 +1,162 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseStep(value string) Result[int] {
+	d := New()
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		d.AddErrorWithCode("PARSE", err)
+		return Fail[int](d)
+	}
+	if result < 0 {
+		d.AddWarning("negative value")
+	}
+
+	return Ok(result, d)
+}
+
+func TestResult(t *testing.T) {
+	t.Run("Ok", func(t *testing.T) {
+		r := Ok(42, nil)
+
+		value, err := r.Unwrap()
+		assert.NoError(t, err, "Unexpected error")
+		assert.Equal(t, 42, value, "Expected the value")
+		assert.False(t, r.Failed(), "Expected the result not to fail")
+		assert.NotNil(t, r.Diagnostics, "Expected a diagnostics")
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		d := New()
+		d.AddError(io.EOF)
+		r := Fail[int](d)
+
+		value, err := r.Unwrap()
+		assert.Error(t, err, "Expected an error")
+		assert.True(t, errors.Is(err, io.EOF), "Expected the error of the diagnostics")
+		assert.Equal(t, 0, value, "Expected no value")
+		assert.True(t, r.Failed(), "Expected the result to fail")
+	})
+
+	t.Run("Fail without errors", func(t *testing.T) {
+		for _, d := range []*Diagnostics{nil, New()} {
+			r := Fail[int](d)
+
+			_, err := r.Unwrap()
+			assert.True(t, r.Failed(), "Expected the result to fail")
+			assert.ErrorIs(t, err, ErrFailed, "Expected ErrFailed")
+		}
+	})
+
+	t.Run("Zero value", func(t *testing.T) {
+		var r Result[string]
+
+		value, err := r.Unwrap()
+		assert.NoError(t, err, "Unexpected error")
+		assert.Equal(t, "", value, "Expected no value")
+	})
+}
+
+func TestMap(t *testing.T) {
+	t.Run("Ok", func(t *testing.T) {
+		r := Map(parseStep("21"), func(value int) int { return value * 2 })
+
+		value, err := r.Unwrap()
+		assert.NoError(t, err, "Unexpected error")
+		assert.Equal(t, 42, value, "Expected the mapped value")
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		called := false
+		r := Map(parseStep("x"), func(value int) string {
+			called = true
+			return strconv.Itoa(value)
+		})
+
+		assert.False(t, called, "Expected fn not to be called")
+		assert.True(t, r.Failed(), "Expected the failure to be kept")
+	})
+}
+
+func TestThen(t *testing.T) {
+	double := func(value int) Result[string] {
+		d := New()
+		d.AddInfo("doubled")
+		return Ok(strconv.Itoa(value*2), d)
+	}
+
+	t.Run("Chains and merges diagnostics", func(t *testing.T) {
+		r := Then(parseStep("-21"), double)
+
+		value, err := r.Unwrap()
+		assert.NoError(t, err, "Unexpected error")
+		assert.Equal(t, "-42", value, "Expected the chained value")
+		assert.Equal(t, []string{"warning: negative value"}, r.Diagnostics.Warnings(), "Expected the warnings of the first step")
+		assert.Equal(t, []string{"doubled"}, r.Diagnostics.Info(), "Expected the info of the second step")
+	})
+
+	t.Run("Short circuits on errors", func(t *testing.T) {
+		called := false
+		r := Then(parseStep("x"), func(value int) Result[string] {
+			called = true
+			return double(value)
+		})
+
+		assert.False(t, called, "Expected fn not to be called")
+		_, err := r.Unwrap()
+		assert.Error(t, err, "Expected the error of the first step")
+		assert.Contains(t, err.Error(), "PARSE", "Expected the error code")
+	})
+
+	t.Run("Failing step", func(t *testing.T) {
+		r := Then(Ok("x", nil), parseStep)
+
+		assert.True(t, r.Failed(), "Expected the failure of the step")
+		assert.Equal(t, 1, len(r.Diagnostics.Errors()), "Expected the errors of the step to be merged")
+	})
+
+	t.Run("Child diagnostics", func(t *testing.T) {
+		d := New()
+		r := Then(Ok(1, d), func(value int) Result[int] {
+			step := d.Child("step")
+			step.AddWarning("w1")
+			return Ok(value+1, step)
+		})
+
+		assert.Same(t, d, r.Diagnostics, "Expected the diagnostics of the pipeline")
+		assert.Equal(t, 1, d.Len(), "Expected the items of the child not to be merged again")
+	})
+
+	t.Run("Shared diagnostics", func(t *testing.T) {
+		d := New()
+		d.AddWarning("warning1")
+		r := Then(Ok(1, d), func(value int) Result[int] {
+			return Ok(value+1, d)
+		})
+
+		assert.Equal(t, 2, r.Value, "Expected the chained value")
+		assert.Equal(t, 1, d.Snapshot()[0].Occurrences, "Expected the diagnostics not to be merged into itself")
+	})
+}
+
+func TestResult_JSON(t *testing.T) {
+	r := parseStep("-1")
+
+	b, err := json.Marshal(r)
+	assert.NoError(t, err, "Unexpected error while marshaling")
+
+	var result Result[int]
+	assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+	assert.Equal(t, -1, result.Value, "Expected the value to round trip")
+	assert.Equal(t, r.Diagnostics.GetTraceID(), result.Diagnostics.GetTraceID(), "Expected the trace id to round trip")
+	assert.Equal(t, []string{"warning: negative value"}, result.Diagnostics.Warnings(), "Expected the items to round trip")
+}
@@ -0,0 +1,182 @@
+package diagnostics
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler records every log record into the diagnostics attached to the
+// record context and then passes it to the next handler, if any.
+type SlogHandler struct {
+	next   slog.Handler
+	attrs  []slog.Attr
+	groups []string
+}
+
+func NewSlogHandler(next slog.Handler) *SlogHandler {
+	return &SlogHandler{
+		next: next,
+	}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if _, ok := diagnosticsFromContext(ctx); ok {
+		return true
+	}
+
+	return h.next != nil && h.next.Enabled(ctx, level)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if d, ok := diagnosticsFromContext(ctx); ok {
+		d.AddItem(h.newItem(record))
+	}
+
+	if h.next != nil && h.next.Enabled(ctx, record.Level) {
+		return h.next.Handle(ctx, record)
+	}
+
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	result := h.clone()
+	result.attrs = append(result.attrs, groupAttributes(h.groups, attrs)...)
+	if h.next != nil {
+		result.next = h.next.WithAttrs(attrs)
+	}
+
+	return result
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	result := h.clone()
+	result.groups = append(result.groups, name)
+	if h.next != nil {
+		result.next = h.next.WithGroup(name)
+	}
+
+	return result
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	return &SlogHandler{
+		next:   h.next,
+		attrs:  append([]slog.Attr{}, h.attrs...),
+		groups: append([]string{}, h.groups...),
+	}
+}
+
+func (h *SlogHandler) newItem(record slog.Record) *DiagnosticItem {
+	item := newDiagnosticItem("", record.Message, levelFromSlog(record.Level))
+	item.pc = record.PC
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		if err, ok := attr.Value.Any().(error); ok && item.Err == nil {
+			item.Err = err
+		}
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	item.Attributes = append(append([]slog.Attr{}, h.attrs...), groupAttributes(h.groups, attrs)...)
+	if len(item.Attributes) == 0 {
+		item.Attributes = nil
+	}
+
+	return item
+}
+
+// Log emits every item through logger, the trace id, metadata, code and scope
+// are added as attributes. The records are logged with a context without the
+// diagnostics so a SlogHandler does not add them back.
+func (d *Diagnostics) Log(logger *slog.Logger) {
+	ctx := NewContext(d.Context(), nil)
+	metadata := d.Metadata()
+	for _, i := range d.Snapshot() {
+		traceID := d.traceID
+		if i.TraceID != "" {
+			traceID = i.TraceID
+		}
+		attrs := []slog.Attr{slog.String(TraceIDAttribute, traceID)}
+		attrs = append(attrs, metadata...)
+		if i.Code != "" {
+			attrs = append(attrs, slog.String(CodeAttribute, i.Code))
+		}
+		if i.Scope != "" {
+			attrs = append(attrs, slog.String(ScopeAttribute, i.Scope))
+		}
+		if i.Occurrences > 1 {
+			attrs = append(attrs, slog.Int(OccurrencesAttribute, i.Occurrences))
+		}
+		attrs = append(attrs, i.Attributes...)
+
+		logger.LogAttrs(ctx, levelToSlog(i.Level), i.Description, attrs...)
+	}
+}
+
+func groupAttributes(groups []string, attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	for i := len(groups) - 1; i >= 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+
+	return attrs
+}
+
+const (
+	slogLevelNotice   = slog.LevelInfo + 2
+	slogLevelCritical = slog.LevelError + 4
+	slogLevelFatal    = slog.LevelError + 8
+	slogLevelTrace    = slog.LevelDebug - 4
+)
+
+func levelFromSlog(level slog.Level) DiagnosticLevel {
+	switch {
+	case level >= slogLevelFatal:
+		return Fatal
+	case level >= slogLevelCritical:
+		return Critical
+	case level >= slog.LevelError:
+		return Error
+	case level >= slog.LevelWarn:
+		return Warning
+	case level >= slogLevelNotice:
+		return Notice
+	case level >= slog.LevelInfo:
+		return Info
+	case level >= slog.LevelDebug:
+		return Debug
+	default:
+		return Trace
+	}
+}
+
+func levelToSlog(level DiagnosticLevel) slog.Level {
+	switch {
+	case level >= Fatal:
+		return slogLevelFatal
+	case level >= Critical:
+		return slogLevelCritical
+	case level >= Error:
+		return slog.LevelError
+	case level >= Warning:
+		return slog.LevelWarn
+	case level >= Notice:
+		return slogLevelNotice
+	case level >= Info:
+		return slog.LevelInfo
+	case level >= Debug:
+		return slog.LevelDebug
+	default:
+		return slogLevelTrace
+	}
+}
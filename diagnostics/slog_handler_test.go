@@ -0,0 +1,120 @@
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler_Handle(t *testing.T) {
+	t.Run("Records into the context diagnostics", func(t *testing.T) {
+		d := New()
+		ctx := context.WithValue(context.Background(), diagnosticsContextKey, d)
+		logger := slog.New(NewSlogHandler(nil))
+
+		logger.DebugContext(ctx, "debug1")
+		logger.InfoContext(ctx, "info1", "userId", 42)
+		logger.WarnContext(ctx, "warning1")
+		logger.ErrorContext(ctx, "error1", "err", io.EOF)
+
+		items := d.GetDiagnostics()
+		assert.Equal(t, 4, len(items), "Expected every record to be added")
+		assert.Equal(t, Debug, items[0].Level, "Expected debug records to be Debug items")
+		assert.Equal(t, Info, items[1].Level, "Expected info records to be Info items")
+		assert.Equal(t, Warning, items[2].Level, "Expected warn records to be Warning items")
+		assert.Equal(t, Error, items[3].Level, "Expected error records to be Error items")
+		assert.Equal(t, "info1", items[1].Description, "Expected the message to be the description")
+		assert.Equal(t, []slog.Attr{slog.Int("userId", 42)}, items[1].Attributes, "Expected the record attributes to be kept")
+		assert.Equal(t, io.EOF, items[3].Err, "Expected the error attribute to be kept as the item error")
+	})
+
+	t.Run("With Attributes and Groups", func(t *testing.T) {
+		d := New()
+		ctx := context.WithValue(context.Background(), diagnosticsContextKey, d)
+		logger := slog.New(NewSlogHandler(nil)).With("tenant", "acme").WithGroup("request").With("method", "GET")
+
+		logger.InfoContext(ctx, "info1", "path", "/users")
+
+		expected := []slog.Attr{
+			slog.String("tenant", "acme"),
+			slog.Group("request", slog.String("method", "GET")),
+			slog.Group("request", slog.String("path", "/users")),
+		}
+		assert.Equal(t, expected, d.GetDiagnostics()[0].Attributes, "Expected the attributes to be grouped")
+	})
+
+	t.Run("Passes the record to the next handler", func(t *testing.T) {
+		var buffer bytes.Buffer
+		d := New()
+		ctx := context.WithValue(context.Background(), diagnosticsContextKey, d)
+		logger := slog.New(NewSlogHandler(slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+		logger.DebugContext(ctx, "debug1")
+		logger.InfoContext(ctx, "info1")
+
+		assert.Equal(t, 2, d.Len(), "Expected both records to be added")
+		assert.NotContains(t, buffer.String(), "debug1", "Expected the next handler level to be respected")
+		assert.Contains(t, buffer.String(), "info1", "Expected the record to be passed to the next handler")
+	})
+
+	t.Run("Without diagnostics in the context", func(t *testing.T) {
+		handler := NewSlogHandler(nil)
+
+		assert.False(t, handler.Enabled(context.Background(), slog.LevelError), "Expected the handler to be disabled")
+		assert.NoError(t, handler.Handle(context.Background(), slog.Record{}), "Unexpected error while handling")
+	})
+}
+
+func TestDiagnostics_Log(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	d := New()
+	d.AddInfo("info1")
+	d.Child("import").AddErrorWithCode("code2", io.EOF)
+	d.AddItem(&DiagnosticItem{Description: "debug1", Level: Debug, Attributes: []slog.Attr{slog.Int("userId", 42)}})
+
+	d.Log(logger)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Equal(t, 3, len(lines), "Expected every item to be logged")
+
+	records := make([]map[string]any, 0, len(lines))
+	for _, line := range lines {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record), "Unexpected error while unmarshaling")
+		assert.Equal(t, d.GetTraceID(), record[TraceIDAttribute], "Expected the trace id attribute")
+		records = append(records, record)
+	}
+
+	assert.Equal(t, "INFO", records[0]["level"], "Expected Info items to be logged as INFO")
+	assert.Equal(t, "ERROR", records[1]["level"], "Expected Error items to be logged as ERROR")
+	assert.Equal(t, "code2", records[1][CodeAttribute], "Expected the code attribute")
+	assert.Equal(t, "import", records[1][ScopeAttribute], "Expected the scope attribute")
+	assert.Equal(t, "DEBUG", records[2]["level"], "Expected Debug items to be logged as DEBUG")
+	assert.Equal(t, float64(42), records[2]["userId"], "Expected the item attributes to be logged")
+}
+
+func TestDiagnostics_Log_SlogHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(NewSlogHandler(slog.NewJSONHandler(&buffer, nil)))
+
+	d := New()
+	d.AddInfo("info1")
+	d.AddWarning("warning1")
+	outer := New()
+
+	d.Log(logger)
+	d.Log(logger.With(slog.String("step", "outer")))
+	logger.InfoContext(outer.Context(), "info2")
+
+	assert.Equal(t, 2, d.Len(), "Expected the logged records not to be added back")
+	assert.Equal(t, 5, len(strings.Split(strings.TrimSpace(buffer.String()), "\n")), "Expected the records to reach the next handler")
+	assert.Equal(t, 1, outer.Len(), "Expected the handler to keep recording other records")
+}
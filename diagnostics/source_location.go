@@ -0,0 +1,120 @@
+package diagnostics
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+type SourceCapture int
+
+const (
+	SourceCaptureOff SourceCapture = iota
+	SourceCaptureOn
+	SourceCaptureErrors
+)
+
+var packagePath = reflect.TypeOf(Diagnostics{}).PkgPath()
+
+type SourceLocation struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function,omitempty"`
+}
+
+func (s SourceLocation) String() string {
+	return fmt.Sprintf("%v:%v", filepath.Base(s.File), s.Line)
+}
+
+func (m SourceCapture) captures(level DiagnosticLevel) bool {
+	switch m {
+	case SourceCaptureOn:
+		return true
+	case SourceCaptureErrors:
+		return level.AtLeast(Error)
+	default:
+		return false
+	}
+}
+
+func sourceFromFrame(frame runtime.Frame) *SourceLocation {
+	return &SourceLocation{
+		File:     frame.File,
+		Line:     frame.Line,
+		Function: frame.Function,
+	}
+}
+
+func sourceFromPC(pc uintptr) *SourceLocation {
+	if pc == 0 {
+		return nil
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return sourceFromFrame(frame)
+}
+
+// callerPC returns the program counter of the caller of the function calling
+// it, without resolving its frame.
+func callerPC() uintptr {
+	pcs := [1]uintptr{}
+	runtime.Callers(3, pcs[:])
+	return pcs[0]
+}
+
+// callerSource returns the location of the first caller outside of this
+// package.
+func callerSource() *SourceLocation {
+	pcs := [32]uintptr{}
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isPackageFrame(frame) {
+			return sourceFromFrame(frame)
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// callerStack returns the stack of the caller without the frames of this
+// package.
+func callerStack() []SourceLocation {
+	return stackFrom(3)
+}
+
+func stackFrom(skip int) []SourceLocation {
+	pcs := [64]uintptr{}
+	n := runtime.Callers(skip, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	result := []SourceLocation{}
+	for {
+		frame, more := frames.Next()
+		if !isPackageFrame(frame) && frame.Function != "runtime.goexit" {
+			result = append(result, *sourceFromFrame(frame))
+		}
+		if !more {
+			return result
+		}
+	}
+}
+
+func writeStackTrace(builder *strings.Builder, stackTrace []SourceLocation) {
+	for _, frame := range stackTrace {
+		builder.WriteString("\t" + frame.Function + "\n")
+		fmt.Fprintf(builder, "\t\t%v:%v\n", frame.File, frame.Line)
+	}
+}
+
+func isPackageFrame(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+
+	return strings.HasPrefix(frame.Function, packagePath+".")
+}
@@ -0,0 +1,161 @@
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func TestSourceLocation_String(t *testing.T) {
+	source := SourceLocation{File: "/src/project/main.go", Line: 42, Function: "main.main"}
+
+	assert.Equal(t, "main.go:42", source.String(), "Expected the file base name and line")
+}
+
+func TestNewDiagnosticItem_Source(t *testing.T) {
+	item, line := NewError("code1", "description1"), currentLine()
+
+	assert.Nil(t, item.Source, "Expected the source not to be resolved before the item is added")
+
+	New(WithSourceCapture(SourceCaptureOn)).AddItem(item)
+
+	assert.NotNil(t, item.Source, "Expected the source to be captured")
+	assert.Equal(t, "source_location_test.go:"+fmt.Sprint(line), item.Source.String(), "Expected the caller location")
+	assert.Equal(t, packagePath+".TestNewDiagnosticItem_Source", item.Source.Function, "Expected the caller function")
+	assert.Equal(t, fmt.Sprintf("[Error] code1: description1 (source_location_test.go:%v)", line), item.String(), "Expected the location in the string representation")
+}
+
+func TestNewDiagnosticItem_Source_Constructors(t *testing.T) {
+	d := New(WithSourceCapture(SourceCaptureOn))
+	line := currentLine()
+	d.AddItem(NewDiagnosticItem("code1", "description1", Info))
+	d.AddItem(NewWarning("code2", "description2"))
+	d.AddItem(NewErrorFrom("code3", errors.New("description3")))
+
+	for index, item := range d.Snapshot() {
+		assert.Equal(t, fmt.Sprintf("source_location_test.go:%v", line+index+1), item.Source.String(), "Expected the constructor caller location")
+	}
+}
+
+func TestDiagnostics_SourceCapture(t *testing.T) {
+	t.Run("Off", func(t *testing.T) {
+		d := New()
+		d.AddError(errors.New("error1"))
+		d.AddItem(NewWarning("", "warning1"))
+
+		for _, i := range d.GetDiagnostics() {
+			assert.Nil(t, i.Source, "Expected no source to be captured")
+		}
+	})
+
+	t.Run("On", func(t *testing.T) {
+		d := New(WithSourceCapture(SourceCaptureOn))
+		d.AddInfo("info1")
+		line := currentLine() - 1
+		d.Child("import").AddError(errors.New("error1"))
+
+		items := d.GetDiagnostics()
+		assert.Equal(t, fmt.Sprintf("source_location_test.go:%v", line), items[0].Source.String(), "Expected the AddInfo caller location")
+		assert.Equal(t, fmt.Sprintf("source_location_test.go:%v", line+2), items[1].Source.String(), "Expected the child to inherit the capture mode")
+		assert.Contains(t, d.String(), fmt.Sprintf("[Info] info1 (source_location_test.go:%v)\n", line), "Expected the location in the string representation")
+	})
+
+	t.Run("Errors only", func(t *testing.T) {
+		d := New(WithSourceCapture(SourceCaptureErrors))
+		d.AddWarning("warning1")
+		d.AddErrorWithCode("code1", errors.New("error1"))
+		d.AddItem(&DiagnosticItem{Description: "error2", Level: Error})
+
+		items := d.GetDiagnostics()
+		assert.Nil(t, items[0].Source, "Expected no source for warnings")
+		assert.NotNil(t, items[1].Source, "Expected the source for errors")
+		assert.NotNil(t, items[2].Source, "Expected the source to be captured when adding an item without one")
+	})
+
+	t.Run("From slog record", func(t *testing.T) {
+		d := New(WithSourceCapture(SourceCaptureOn))
+		ctx := context.WithValue(context.Background(), diagnosticsContextKey, d)
+
+		slog.New(NewSlogHandler(nil)).InfoContext(ctx, "info1")
+		line := currentLine() - 1
+
+		assert.Equal(t, fmt.Sprintf("source_location_test.go:%v", line), d.GetDiagnostics()[0].Source.String(), "Expected the record location")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		item := &DiagnosticItem{Description: "description1", Level: Error, Source: &SourceLocation{File: "/src/main.go", Line: 42, Function: "main.main"}}
+
+		b, err := json.Marshal(item)
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		assert.Equal(t, `{"description":"description1","level":"Error","source":{"file":"/src/main.go","line":42,"function":"main.main"}}`, string(b), "Unexpected marshaled JSON")
+
+		var result DiagnosticItem
+		assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+		assert.Equal(t, item.Source, result.Source, "Expected the source to be kept")
+	})
+}
+
+func TestDiagnostics_StackTrace(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		d := New()
+		d.AddError(errors.New("error1"))
+
+		assert.Nil(t, d.GetDiagnostics()[0].StackTrace, "Expected no stack trace")
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		d := New(WithStackTrace())
+		d.AddWarning("warning1")
+		d.AddErrorWithCode("code1", errors.New("error1"))
+		line := currentLine() - 1
+
+		items := d.GetDiagnostics()
+		assert.Nil(t, items[0].StackTrace, "Expected no stack trace for warnings")
+		assert.NotEmpty(t, items[1].StackTrace, "Expected the stack trace for errors")
+
+		frame := items[1].StackTrace[0]
+		assert.Equal(t, packagePath+".TestDiagnostics_StackTrace.func2", frame.Function, "Expected the package frames to be trimmed")
+		assert.Equal(t, line, frame.Line, "Expected the first frame to be the caller")
+		for _, f := range items[1].StackTrace {
+			assert.NotEqual(t, "runtime.goexit", f.Function, "Expected runtime.goexit to be trimmed")
+		}
+
+		expected := fmt.Sprintf("[Error] code1: error1\n\t%v\n\t\t%v:%v\n", frame.Function, frame.File, frame.Line)
+		assert.Contains(t, d.String(), expected, "Expected the stack trace in the string representation")
+	})
+
+	t.Run("Inherited by children", func(t *testing.T) {
+		d := New(WithStackTrace())
+		d.Child("import").AddError(errors.New("error1"))
+
+		assert.NotEmpty(t, d.GetDiagnostics()[0].StackTrace, "Expected the child to capture the stack trace")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		item := &DiagnosticItem{Description: "description1", Level: Error, StackTrace: []SourceLocation{
+			{File: "/src/main.go", Line: 42, Function: "main.run"},
+			{File: "/src/main.go", Line: 10, Function: "main.main"},
+		}}
+
+		b, err := json.Marshal(item)
+		expected := `{"description":"description1","level":"Error","stackTrace":[` +
+			`{"file":"/src/main.go","line":42,"function":"main.run"},{"file":"/src/main.go","line":10,"function":"main.main"}]}`
+		assert.NoError(t, err, "Unexpected error while marshaling")
+		assert.Equal(t, expected, string(b), "Unexpected marshaled JSON")
+
+		var result DiagnosticItem
+		assert.NoError(t, json.Unmarshal(b, &result), "Unexpected error while unmarshaling")
+		assert.Equal(t, item.StackTrace, result.StackTrace, "Expected the stack trace to be kept")
+	})
+}
@@ -0,0 +1,198 @@
+package diagnostics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidTraceParent = errors.New("invalid traceparent")
+
+const traceContextVersion = "00"
+
+const (
+	maxTraceStateMembers = 32
+	maxTraceStateLength  = 512
+)
+
+var (
+	traceStateKey   = regexp.MustCompile(`^([a-z][_0-9a-z\-*/]{0,255}|[a-z0-9][_0-9a-z\-*/]{0,240}@[a-z][_0-9a-z\-*/]{0,13})$`)
+	traceStateValue = regexp.MustCompile(`^[\x20-\x2b\x2d-\x3c\x3e-\x7e]{0,255}[\x21-\x2b\x2d-\x3c\x3e-\x7e]$`)
+)
+
+// TraceContext holds a W3C Trace Context, see
+// https://www.w3.org/TR/trace-context/
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	State   string
+}
+
+func NewTraceContext() TraceContext {
+	result := TraceContext{
+		Flags: 0x01,
+	}
+
+	randomID(result.TraceID[:])
+	result.SpanID = newSpanID()
+
+	return result
+}
+
+func ParseTraceParent(traceParent string) (TraceContext, error) {
+	result := TraceContext{}
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 {
+		return result, fmt.Errorf("%w: %q", ErrInvalidTraceParent, traceParent)
+	}
+
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 || version[0] == 0xff {
+		return result, fmt.Errorf("%w: invalid version %q", ErrInvalidTraceParent, parts[0])
+	}
+	if parts[0] == traceContextVersion && len(parts) != 4 {
+		return result, fmt.Errorf("%w: %q", ErrInvalidTraceParent, traceParent)
+	}
+
+	if err := decodeTraceParentField(result.TraceID[:], parts[1]); err != nil {
+		return result, fmt.Errorf("%w: invalid trace id %q", ErrInvalidTraceParent, parts[1])
+	}
+	if err := decodeTraceParentField(result.SpanID[:], parts[2]); err != nil {
+		return result, fmt.Errorf("%w: invalid parent id %q", ErrInvalidTraceParent, parts[2])
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return result, fmt.Errorf("%w: invalid flags %q", ErrInvalidTraceParent, parts[3])
+	}
+	result.Flags = flags[0]
+
+	return result, nil
+}
+
+func ParseTraceContext(traceParent string, traceState string) (TraceContext, error) {
+	result, err := ParseTraceParent(traceParent)
+	if err != nil {
+		return result, err
+	}
+
+	result.State, _ = parseTraceState(traceState)
+	return result, nil
+}
+
+// parseTraceState returns the list members of a tracestate header joined by
+// commas, a malformed header is dropped as a whole.
+func parseTraceState(traceState string) (string, bool) {
+	if len(traceState) > maxTraceStateLength {
+		return "", false
+	}
+
+	members := []string{}
+	keys := map[string]bool{}
+	for _, member := range strings.Split(traceState, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(member, "=")
+		if !ok || keys[key] || !traceStateKey.MatchString(key) || !traceStateValue.MatchString(value) {
+			return "", false
+		}
+		keys[key] = true
+		members = append(members, member)
+	}
+	if len(members) > maxTraceStateMembers {
+		return "", false
+	}
+
+	return strings.Join(members, ","), true
+}
+
+func decodeTraceParentField(dst []byte, value string) error {
+	if len(value) != hex.EncodedLen(len(dst)) || strings.ToLower(value) != value {
+		return ErrInvalidTraceParent
+	}
+	if _, err := hex.Decode(dst, []byte(value)); err != nil {
+		return err
+	}
+	for _, b := range dst {
+		if b != 0 {
+			return nil
+		}
+	}
+
+	return ErrInvalidTraceParent
+}
+
+// parseTraceID accepts 32 hex digit trace ids, with or without the uuid dashes.
+func parseTraceID(value string) ([16]byte, bool) {
+	result := [16]byte{}
+	err := decodeTraceParentField(result[:], strings.ToLower(strings.ReplaceAll(value, "-", "")))
+	return result, err == nil
+}
+
+func newSpanID() [8]byte {
+	result := [8]byte{}
+	randomID(result[:])
+	return result
+}
+
+// randomID fills dst with random bytes, all zeros being an invalid id.
+func randomID(dst []byte) {
+	for {
+		if _, err := rand.Read(dst); err != nil {
+			panic(err)
+		}
+		for _, b := range dst {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
+
+// NewSpan returns a trace context for a new span of the same trace.
+func (t TraceContext) NewSpan() TraceContext {
+	result := t
+	result.SpanID = newSpanID()
+	return result
+}
+
+func (t TraceContext) TraceIDString() string {
+	return hex.EncodeToString(t.TraceID[:])
+}
+
+func (t TraceContext) SpanIDString() string {
+	return hex.EncodeToString(t.SpanID[:])
+}
+
+func (t TraceContext) Sampled() bool {
+	return t.Flags&0x01 == 0x01
+}
+
+func (t TraceContext) TraceParent() string {
+	return fmt.Sprintf("%v-%v-%v-%02x", traceContextVersion, t.TraceIDString(), t.SpanIDString(), t.Flags)
+}
+
+func (t TraceContext) String() string {
+	return t.TraceParent()
+}
+
+func ContextWithTraceContext(ctx context.Context, traceContext TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextContextKey, traceContext)
+}
+
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+
+	result, ok := ctx.Value(traceContextContextKey).(TraceContext)
+	return result, ok
+}
@@ -0,0 +1,174 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTraceContext(t *testing.T) {
+	traceContext := NewTraceContext()
+
+	assert.NotEqual(t, [16]byte{}, traceContext.TraceID, "Expected the trace id to be non-zero")
+	assert.NotEqual(t, [8]byte{}, traceContext.SpanID, "Expected the span id to be non-zero")
+	assert.True(t, traceContext.Sampled(), "Expected the trace context to be sampled")
+	assert.Equal(t, 32, len(traceContext.TraceIDString()), "Expected the trace id to have 32 hex digits")
+	assert.Equal(t, 16, len(traceContext.SpanIDString()), "Expected the span id to have 16 hex digits")
+}
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceParent string
+		valid       bool
+	}{
+		{name: "Valid", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true},
+		{name: "Valid not sampled", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{name: "Future version with extra fields", traceParent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true},
+		{name: "Empty", traceParent: "", valid: false},
+		{name: "Invalid version", traceParent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: false},
+		{name: "Version 00 with extra fields", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: false},
+		{name: "Zero trace id", traceParent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", valid: false},
+		{name: "Zero span id", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", valid: false},
+		{name: "Uppercase trace id", traceParent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", valid: false},
+		{name: "Short trace id", traceParent: "00-4bf92f3577b34da6-00f067aa0ba902b7-01", valid: false},
+		{name: "Invalid flags", traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceContext, err := ParseTraceParent(tt.traceParent)
+			if !tt.valid {
+				assert.True(t, errors.Is(err, ErrInvalidTraceParent), "Expected an invalid traceparent error")
+				return
+			}
+
+			assert.NoError(t, err, "Unexpected error while parsing")
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceContext.TraceIDString(), "Unexpected trace id")
+			assert.Equal(t, "00f067aa0ba902b7", traceContext.SpanIDString(), "Unexpected span id")
+		})
+	}
+}
+
+func TestTraceContext_TraceParent(t *testing.T) {
+	traceContext, err := ParseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=value")
+
+	assert.NoError(t, err, "Unexpected error while parsing")
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", traceContext.TraceParent(), "Expected the same traceparent")
+	assert.Equal(t, "vendor=value", traceContext.State, "Expected the tracestate to be kept")
+}
+
+func TestParseTraceContext_TraceState(t *testing.T) {
+	tests := []struct {
+		name       string
+		traceState string
+		expected   string
+	}{
+		{name: "Single member", traceState: "vendor=value", expected: "vendor=value"},
+		{name: "Several members", traceState: " rojo=00f067aa0ba902b7 ,, congo=t61rcWkgMzE,tenant@vendor=a/b ", expected: "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE,tenant@vendor=a/b"},
+		{name: "Empty", traceState: "", expected: ""},
+		{name: "Uppercase key", traceState: "Vendor=value", expected: ""},
+		{name: "Missing value", traceState: "vendor", expected: ""},
+		{name: "Invalid value", traceState: "vendor=a=b", expected: ""},
+		{name: "Duplicate key", traceState: "vendor=a,vendor=b", expected: ""},
+		{name: "Too many members", traceState: strings.Repeat("k=v,", 33), expected: ""},
+		{name: "Too long", traceState: "vendor=" + strings.Repeat("a", 510), expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceContext, err := ParseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tt.traceState)
+
+			assert.NoError(t, err, "Expected the traceparent to be kept")
+			assert.Equal(t, tt.expected, traceContext.State, "Unexpected tracestate")
+		})
+	}
+}
+
+func TestNewTraceContext_Random(t *testing.T) {
+	versions := map[byte]bool{}
+	for i := 0; i < 64; i++ {
+		traceContext := NewTraceContext()
+		versions[traceContext.SpanID[6]>>4] = true
+		versions[traceContext.TraceID[6]>>4+16] = true
+	}
+
+	assert.Greater(t, len(versions), 4, "Expected the ids not to have fixed bits")
+}
+
+func TestTraceContext_NewSpan(t *testing.T) {
+	traceContext, _ := ParseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=value")
+	span := traceContext.NewSpan()
+
+	assert.Equal(t, traceContext.TraceID, span.TraceID, "Expected the span to keep the trace id")
+	assert.NotEqual(t, traceContext.SpanID, span.SpanID, "Expected the span to have a new span id")
+	assert.Equal(t, traceContext.Flags, span.Flags, "Expected the span to keep the flags")
+	assert.Equal(t, traceContext.State, span.State, "Expected the span to keep the tracestate")
+}
+
+func TestTraceContextFromContext(t *testing.T) {
+	_, ok := TraceContextFromContext(context.Background())
+	assert.False(t, ok, "Expected no trace context")
+
+	traceContext := NewTraceContext()
+	result, ok := TraceContextFromContext(ContextWithTraceContext(context.Background(), traceContext))
+	assert.True(t, ok, "Expected a trace context")
+	assert.Equal(t, traceContext, result, "Expected the same trace context")
+}
+
+func TestDiagnostics_W3CTraceContext(t *testing.T) {
+	t.Run("Without W3C trace context", func(t *testing.T) {
+		d := New()
+
+		_, ok := d.TraceContext()
+		assert.False(t, ok, "Expected no trace context")
+		assert.Empty(t, d.TraceParent(), "Expected an empty traceparent")
+		assert.Empty(t, d.SpanID(), "Expected an empty span id")
+	})
+
+	t.Run("New with W3C trace context", func(t *testing.T) {
+		d := New(WithW3CTraceContext())
+		traceContext, ok := d.TraceContext()
+
+		assert.True(t, ok, "Expected a trace context")
+		assert.Equal(t, traceContext.TraceIDString(), d.GetTraceID(), "Expected the trace id to be the W3C trace id")
+		assert.Equal(t, d.GetTraceID(), d.Context().Value(TraceID), "Expected the context to carry the trace id")
+		assert.Equal(t, traceContext.TraceParent(), d.TraceParent(), "Expected the traceparent of the trace context")
+	})
+
+	t.Run("From context with existing uuid trace id", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), TraceID, "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+		d := FromContext(ctx, WithW3CTraceContext())
+
+		assert.Equal(t, "6ba7b8109dad11d180b400c04fd430c8", d.GetTraceID(), "Expected the trace id to be reused")
+	})
+
+	t.Run("From context with trace context", func(t *testing.T) {
+		parent, _ := ParseTraceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "vendor=value")
+		d := FromContext(ContextWithTraceContext(context.Background(), parent))
+		traceContext, ok := d.TraceContext()
+
+		assert.True(t, ok, "Expected a trace context")
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", d.GetTraceID(), "Expected the trace id to be reused")
+		assert.NotEqual(t, parent.SpanID, traceContext.SpanID, "Expected a new span")
+		assert.Equal(t, "vendor=value", d.TraceState(), "Expected the tracestate to be kept")
+
+		fromContext, _ := TraceContextFromContext(d.Context())
+		assert.Equal(t, traceContext, fromContext, "Expected the context to carry the new span")
+	})
+
+	t.Run("Child spans", func(t *testing.T) {
+		d := New(WithW3CTraceContext())
+		child := d.Child("import")
+
+		assert.Equal(t, d.GetTraceID(), child.GetTraceID(), "Expected the child to share the trace id")
+		assert.NotEmpty(t, child.SpanID(), "Expected the child to have a span id")
+		assert.NotEqual(t, d.SpanID(), child.SpanID(), "Expected the child to have its own span id")
+
+		fromContext, _ := TraceContextFromContext(child.Context())
+		assert.Equal(t, child.SpanID(), fromContext.SpanIDString(), "Expected the child context to carry the child span")
+	})
+}
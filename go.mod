@@ -1,6 +1,6 @@
 module github.com/cjlapao/common-go-diagnostics
 
-go 1.21
+go 1.23
 
 require (
 	github.com/google/uuid v1.3.0